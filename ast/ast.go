@@ -5,6 +5,7 @@ package ast
 import (
 	"bytes"
 	"monkey/token"
+	"monkey/utils"
 	"strings"
 )
 
@@ -46,9 +47,10 @@ func (p *Program) String() string {
 }
 
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token    token.Token
+	Name     *Identifier
+	Value    Expression
+	Exported bool // written as export let, to be seen by modules importing this one
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -60,8 +62,11 @@ func (ls *LetStatement) TokenLiteral() string {
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
+	if ls.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String() + " = ")
+	out.WriteString(annotated(ls.Name) + " = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
 	}
@@ -69,9 +74,34 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// ImportStatement binds Name to the module at Path.
+type ImportStatement struct {
+	Token token.Token // the 'import' token
+	Path  *StringLiteral
+	Name  *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " " + utils.Quote(is.Path.Value) + " as " + is.Name.String() + ";"
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
+
+	// Type is the annotation of a let name or parameter; nil if it has
+	// none.
+	Type TypeExpression
+
+	// Symbol is what the identifier refers to, as worked out by the
+	// resolver package; nil if the identifier has not been resolved.
+	Symbol *Symbol
 }
 
 func (i *Identifier) expressionNode() {}
@@ -211,6 +241,16 @@ func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 
 func (b *Boolean) String() string { return b.Token.Literal }
 
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) expressionNode() {}
+
+func (n *NullLiteral) TokenLiteral() string { return n.Token.Literal }
+
+func (n *NullLiteral) String() string { return n.Token.Literal }
+
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
@@ -241,8 +281,9 @@ func (ie *IfExpression) String() string {
 }
 
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // the '{' token
 	Statements []Statement
+	Rbrace     token.Token // the '}' token
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -264,7 +305,12 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	ReturnType TypeExpression // nil if the result is not annotated
 	Body       *BlockStatement
+
+	// Scope is the scope of the body, set by the resolver package; nil if
+	// the function has not been resolved.
+	Scope *Scope
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -278,11 +324,14 @@ func (fl *FunctionLiteral) String() string {
 
 	var params []string
 	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+		params = append(params, annotated(p))
 	}
 
 	out.WriteString(fl.TokenLiteral() + "(")
 	out.WriteString(strings.Join(params, ", ") + ") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -390,6 +439,11 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 }
 
+// Keys returns the keys of the hash in the order they appear in the source.
+func (hl *HashLiteral) Keys() []Expression {
+	return sortedKeys(hl)
+}
+
 type MacroLiteral struct {
 	Token 	token.Token
 	Parameters []*Identifier
@@ -0,0 +1,86 @@
+//ast/copy.go
+
+package ast
+
+// Copy returns a deep copy of the tree rooted at node, so that the copy can
+// be modified without affecting the original.
+func Copy(node Node) Node {
+	if node == nil {
+		return nil
+	}
+
+	var copied Node
+	switch node := node.(type) {
+	case *Program:
+		n := *node
+		n.Statements = append([]Statement(nil), node.Statements...)
+		copied = &n
+	case *LetStatement:
+		n := *node
+		copied = &n
+	case *ReturnStatement:
+		n := *node
+		copied = &n
+	case *ImportStatement:
+		n := *node
+		copied = &n
+	case *ExpressionStatement:
+		n := *node
+		copied = &n
+	case *BlockStatement:
+		n := *node
+		n.Statements = append([]Statement(nil), node.Statements...)
+		copied = &n
+	case *Identifier:
+		n := *node
+		copied = &n
+	case *IntegerLiteral:
+		n := *node
+		copied = &n
+	case *StringLiteral:
+		n := *node
+		copied = &n
+	case *Boolean:
+		n := *node
+		copied = &n
+	case *NullLiteral:
+		n := *node
+		copied = &n
+	case *PrefixExpression:
+		n := *node
+		copied = &n
+	case *InfixExpression:
+		n := *node
+		copied = &n
+	case *IfExpression:
+		n := *node
+		copied = &n
+	case *FunctionLiteral:
+		n := *node
+		n.Parameters = append([]*Identifier(nil), node.Parameters...)
+		copied = &n
+	case *MacroLiteral:
+		n := *node
+		n.Parameters = append([]*Identifier(nil), node.Parameters...)
+		copied = &n
+	case *CallExpression:
+		n := *node
+		n.Arguments = append([]Expression(nil), node.Arguments...)
+		copied = &n
+	case *ArrayLiteral:
+		n := *node
+		n.Elements = append([]Expression(nil), node.Elements...)
+		copied = &n
+	case *IndexExpression:
+		n := *node
+		copied = &n
+	case *HashLiteral:
+		n := *node
+		copied = &n
+	default:
+		return node
+	}
+
+	ModifyChildren(copied, Copy)
+	return copied
+}
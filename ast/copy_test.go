@@ -0,0 +1,42 @@
+// ast/copy_test.go
+
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	original := &FunctionLiteral{
+		Parameters: []*Identifier{{Value: "x"}},
+		Body: &BlockStatement{
+			Statements: []Statement{
+				&ExpressionStatement{
+					Expression: &CallExpression{
+						Function:  &Identifier{Value: "f"},
+						Arguments: []Expression{&IntegerLiteral{Value: 1}},
+					},
+				},
+			},
+		},
+	}
+
+	copied := Copy(original)
+	if !reflect.DeepEqual(copied, original) {
+		t.Fatalf("copy differs from original. got=%#v", copied)
+	}
+
+	Modify(copied, func(node Node) Node {
+		if integer, ok := node.(*IntegerLiteral); ok {
+			integer.Value = 2
+		}
+		return node
+	})
+
+	stmt := original.Body.Statements[0].(*ExpressionStatement)
+	arg := stmt.Expression.(*CallExpression).Arguments[0].(*IntegerLiteral)
+	if arg.Value != 1 {
+		t.Errorf("modifying the copy changed the original. got=%d", arg.Value)
+	}
+}
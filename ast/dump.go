@@ -0,0 +1,109 @@
+//ast/dump.go
+
+package ast
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Dump writes the tree rooted at node to w, one node per line and indented
+// by depth, e.g.
+//
+//	Program
+//	  LetStatement
+//	    Identifier x
+//	    IntegerLiteral 5
+func Dump(w io.Writer, node Node) {
+	dump(w, node, 0)
+}
+
+func dump(w io.Writer, node Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if node == nil {
+		fmt.Fprintf(w, "%s<nil>\n", indent)
+		return
+	}
+
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	if detail := dumpDetail(node); detail != "" {
+		fmt.Fprintf(w, "%s%s %s\n", indent, name, detail)
+	} else {
+		fmt.Fprintf(w, "%s%s\n", indent, name)
+	}
+
+	for _, child := range children(node) {
+		dump(w, child, depth+1)
+	}
+}
+
+func dumpDetail(node Node) string {
+	switch node := node.(type) {
+	case *LetStatement:
+		if node.Exported {
+			return "export"
+		}
+		return ""
+	case *Identifier:
+		return annotated(node)
+	case *FunctionLiteral:
+		if node.ReturnType != nil {
+			return "-> " + node.ReturnType.String()
+		}
+		return ""
+	case *IntegerLiteral:
+		return node.Token.Literal
+	case *StringLiteral:
+		return fmt.Sprintf("%q", node.Value)
+	case *Boolean:
+		return node.Token.Literal
+	case *PrefixExpression:
+		return node.Operator
+	case *InfixExpression:
+		return node.Operator
+	default:
+		return ""
+	}
+}
+
+// children returns the direct children of node in source order.
+func children(node Node) []Node {
+	var result []Node
+	if hash, ok := node.(*HashLiteral); ok {
+		for _, key := range sortedKeys(hash) {
+			result = append(result, key, hash.Pairs[key])
+		}
+		return result
+	}
+
+	ModifyChildren(node, func(child Node) Node {
+		if child != nil {
+			result = append(result, child)
+		}
+		return child
+	})
+	return result
+}
+
+// sortedKeys returns the keys of hash in source order.
+func sortedKeys(hash *HashLiteral) []Expression {
+	keys := make([]Expression, 0, len(hash.Pairs))
+	for key := range hash.Pairs {
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return tokenBefore(keys[i], keys[j])
+	})
+	return keys
+}
+
+// tokenBefore orders nodes by the source position of their token.
+func tokenBefore(a, b Node) bool {
+	ta, tb := tokenOf(a), tokenOf(b)
+	if ta.Line != tb.Line {
+		return ta.Line < tb.Line
+	}
+	return ta.Column < tb.Column
+}
@@ -0,0 +1,50 @@
+// ast/dump_test.go
+
+package ast
+
+import (
+	"bytes"
+	"monkey/token"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name:  &Identifier{Value: "x"},
+				Value: &InfixExpression{
+					Left:     &IntegerLiteral{Token: token.Token{Literal: "1"}, Value: 1},
+					Operator: "+",
+					Right: &HashLiteral{
+						Pairs: map[Expression]Expression{
+							&StringLiteral{Token: token.Token{Line: 1, Column: 2}, Value: "b"}: &Boolean{Token: token.Token{Literal: "true"}},
+							&StringLiteral{Token: token.Token{Line: 1, Column: 1}, Value: "a"}: &NullLiteral{},
+						},
+					},
+				},
+			},
+			&ReturnStatement{},
+		},
+	}
+
+	expected := `Program
+  LetStatement
+    Identifier x
+    InfixExpression +
+      IntegerLiteral 1
+      HashLiteral
+        StringLiteral "a"
+        NullLiteral
+        StringLiteral "b"
+        Boolean true
+  ReturnStatement
+`
+
+	var out bytes.Buffer
+	Dump(&out, program)
+	if out.String() != expected {
+		t.Errorf("wrong dump. want=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,153 @@
+//ast/json.go
+
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarshalJSON encodes the tree rooted at node as JSON. Every node becomes an
+// object with its "type", the "line" and "column" of its token, and a field
+// for each of its children and values, e.g.
+//
+//	{"type": "Identifier", "line": 1, "column": 5, "value": "x"}
+//
+// Hash literals list their pairs in source order as {"key", "value"}
+// objects. Type annotations are written as strings, in the "annotation" of
+// identifiers and the "returnType" of function literals.
+func MarshalJSON(node Node) ([]byte, error) {
+	return json.Marshal(jsonNode(node))
+}
+
+func jsonNode(node Node) interface{} {
+	if node == nil {
+		return nil
+	}
+	tok := tokenOf(node)
+	result := map[string]interface{}{
+		"type": strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."),
+	}
+	if _, ok := node.(*Program); !ok {
+		result["line"] = tok.Line
+		result["column"] = tok.Column
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		result["statements"] = jsonStatements(node.Statements)
+	case *LetStatement:
+		result["name"] = jsonIdentifier(node.Name)
+		result["value"] = jsonExpression(node.Value)
+		if node.Exported {
+			result["exported"] = true
+		}
+	case *ImportStatement:
+		result["path"] = node.Path.Value
+		result["name"] = jsonIdentifier(node.Name)
+	case *ReturnStatement:
+		result["value"] = jsonExpression(node.ReturnValue)
+	case *ExpressionStatement:
+		result["expression"] = jsonExpression(node.Expression)
+	case *BlockStatement:
+		result["statements"] = jsonStatements(node.Statements)
+	case *Identifier:
+		result["value"] = node.Value
+		if node.Type != nil {
+			result["annotation"] = node.Type.String()
+		}
+	case *IntegerLiteral:
+		result["value"] = node.Value
+	case *StringLiteral:
+		result["value"] = node.Value
+	case *Boolean:
+		result["value"] = node.Value
+	case *PrefixExpression:
+		result["operator"] = node.Operator
+		result["right"] = jsonExpression(node.Right)
+	case *InfixExpression:
+		result["left"] = jsonExpression(node.Left)
+		result["operator"] = node.Operator
+		result["right"] = jsonExpression(node.Right)
+	case *IfExpression:
+		result["condition"] = jsonExpression(node.Condition)
+		result["consequence"] = jsonBlock(node.Consequence)
+		result["alternative"] = jsonBlock(node.Alternative)
+	case *FunctionLiteral:
+		result["parameters"] = jsonIdentifiers(node.Parameters)
+		if node.ReturnType != nil {
+			result["returnType"] = node.ReturnType.String()
+		}
+		result["body"] = jsonBlock(node.Body)
+	case *MacroLiteral:
+		result["parameters"] = jsonIdentifiers(node.Parameters)
+		result["body"] = jsonBlock(node.Body)
+	case *CallExpression:
+		result["function"] = jsonExpression(node.Function)
+		result["arguments"] = jsonExpressions(node.Arguments)
+	case *ArrayLiteral:
+		result["elements"] = jsonExpressions(node.Elements)
+	case *IndexExpression:
+		result["left"] = jsonExpression(node.Left)
+		result["index"] = jsonExpression(node.Index)
+	case *HashLiteral:
+		pairs := []interface{}{}
+		for _, key := range sortedKeys(node) {
+			pairs = append(pairs, map[string]interface{}{
+				"key":   jsonNode(key),
+				"value": jsonExpression(node.Pairs[key]),
+			})
+		}
+		result["pairs"] = pairs
+	}
+	return result
+}
+
+// The helpers below keep nil children as JSON null rather than typed nils
+// wrapped in a Node.
+
+func jsonExpression(e Expression) interface{} {
+	if e == nil {
+		return nil
+	}
+	return jsonNode(e)
+}
+
+func jsonBlock(b *BlockStatement) interface{} {
+	if b == nil {
+		return nil
+	}
+	return jsonNode(b)
+}
+
+func jsonIdentifier(ident *Identifier) interface{} {
+	if ident == nil {
+		return nil
+	}
+	return jsonNode(ident)
+}
+
+func jsonStatements(statements []Statement) []interface{} {
+	result := []interface{}{}
+	for _, s := range statements {
+		result = append(result, jsonNode(s))
+	}
+	return result
+}
+
+func jsonExpressions(expressions []Expression) []interface{} {
+	result := []interface{}{}
+	for _, e := range expressions {
+		result = append(result, jsonExpression(e))
+	}
+	return result
+}
+
+func jsonIdentifiers(identifiers []*Identifier) []interface{} {
+	result := []interface{}{}
+	for _, ident := range identifiers {
+		result = append(result, jsonIdentifier(ident))
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+// ast/json_test.go
+
+package ast
+
+import (
+	"monkey/token"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+				Name:  &Identifier{Token: token.Token{Line: 1, Column: 5}, Value: "x"},
+				Value: &CallExpression{
+					Token:    token.Token{Line: 1, Column: 10},
+					Function: &Identifier{Token: token.Token{Line: 1, Column: 9}, Value: "f"},
+					Arguments: []Expression{
+						&HashLiteral{
+							Token: token.Token{Line: 1, Column: 11},
+							Pairs: map[Expression]Expression{
+								&StringLiteral{Token: token.Token{Line: 1, Column: 20}, Value: "b"}: &Boolean{Value: true},
+								&StringLiteral{Token: token.Token{Line: 1, Column: 12}, Value: "a"}: &IntegerLiteral{Value: 1},
+							},
+						},
+					},
+				},
+			},
+			&ReturnStatement{},
+		},
+	}
+
+	data, err := MarshalJSON(program)
+	if err != nil {
+		t.Fatalf("MarshalJSON returned %v", err)
+	}
+
+	expected := `{"statements":[` +
+		`{"column":1,"line":1,"name":{"column":5,"line":1,"type":"Identifier","value":"x"},"type":"LetStatement",` +
+		`"value":{"arguments":[{"column":11,"line":1,"pairs":[` +
+		`{"key":{"column":12,"line":1,"type":"StringLiteral","value":"a"},"value":{"column":0,"line":0,"type":"IntegerLiteral","value":1}},` +
+		`{"key":{"column":20,"line":1,"type":"StringLiteral","value":"b"},"value":{"column":0,"line":0,"type":"Boolean","value":true}}` +
+		`],"type":"HashLiteral"}],"column":10,"function":{"column":9,"line":1,"type":"Identifier","value":"f"},"line":1,"type":"CallExpression"}},` +
+		`{"column":0,"line":0,"type":"ReturnStatement","value":null}` +
+		`],"type":"Program"}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot=%s", expected, data)
+	}
+}
@@ -4,53 +4,78 @@ package ast
 
 type ModifyFunc func(Node) Node
 
+// Modify walks the tree rooted at node depth-first and replaces every node
+// with the result of calling modifier on it, children before parents.
 func Modify(node Node, modifier ModifyFunc) Node {
+	ModifyChildren(node, func(child Node) Node {
+		return Modify(child, modifier)
+	})
+	return modifier(node)
+}
+
+// ModifyChildren replaces each direct child of node with the result of
+// calling modifier on it. Unlike Modify it does not recurse, which lets the
+// caller decide what to do before and after descending into a subtree.
+func ModifyChildren(node Node, modifier ModifyFunc) {
 	switch node := node.(type) {
 	case *Program:
 		for i, stmt := range node.Statements {
-			node.Statements[i], _ = Modify(stmt, modifier).(Statement)
+			node.Statements[i], _ = modifier(stmt).(Statement)
 		}
 	case *ExpressionStatement:
-		node.Expression, _ = Modify(node.Expression, modifier).(Expression)
+		node.Expression, _ = modifier(node.Expression).(Expression)
 	case *InfixExpression:
-		node.Left, _ = Modify(node.Left, modifier).(Expression)
-		node.Right, _ = Modify(node.Right, modifier).(Expression)
+		node.Left, _ = modifier(node.Left).(Expression)
+		node.Right, _ = modifier(node.Right).(Expression)
 	case *PrefixExpression:
-		node.Right, _ = Modify(node.Right, modifier).(Expression)
+		node.Right, _ = modifier(node.Right).(Expression)
 	case *IndexExpression:
-		node.Left, _ = Modify(node.Left, modifier).(Expression)
-		node.Index, _ = Modify(node.Index, modifier).(Expression)
+		node.Left, _ = modifier(node.Left).(Expression)
+		node.Index, _ = modifier(node.Index).(Expression)
 	case *IfExpression:
-		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
-		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		node.Condition, _ = modifier(node.Condition).(Expression)
+		node.Consequence, _ = modifier(node.Consequence).(*BlockStatement)
 		if node.Alternative != nil {
-			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+			node.Alternative, _ = modifier(node.Alternative).(*BlockStatement)
 		}
 	case *BlockStatement:
 		for i, stmt := range node.Statements {
-			node.Statements[i], _ = Modify(stmt, modifier).(Statement)
+			node.Statements[i], _ = modifier(stmt).(Statement)
 		}
 	case *ReturnStatement:
-		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+		node.ReturnValue, _ = modifier(node.ReturnValue).(Expression)
 	case *LetStatement:
-		node.Value, _ = Modify(node.Value, modifier).(Expression)
+		node.Name, _ = modifier(node.Name).(*Identifier)
+		node.Value, _ = modifier(node.Value).(Expression)
+	case *ImportStatement:
+		node.Path, _ = modifier(node.Path).(*StringLiteral)
+		node.Name, _ = modifier(node.Name).(*Identifier)
 	case *FunctionLiteral:
-		for i, _ := range node.Parameters {
-			node.Parameters[i], _ = Modify(node.Parameters[i], modifier).(*Identifier)
+		for i := range node.Parameters {
+			node.Parameters[i], _ = modifier(node.Parameters[i]).(*Identifier)
+		}
+		node.Body, _ = modifier(node.Body).(*BlockStatement)
+	case *MacroLiteral:
+		for i := range node.Parameters {
+			node.Parameters[i], _ = modifier(node.Parameters[i]).(*Identifier)
+		}
+		node.Body, _ = modifier(node.Body).(*BlockStatement)
+	case *CallExpression:
+		node.Function, _ = modifier(node.Function).(Expression)
+		for i, arg := range node.Arguments {
+			node.Arguments[i], _ = modifier(arg).(Expression)
 		}
-		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
 	case *ArrayLiteral:
 		for i, elem := range node.Elements {
-			node.Elements[i], _ = Modify(elem, modifier).(Expression)
+			node.Elements[i], _ = modifier(elem).(Expression)
 		}
 	case *HashLiteral:
 		newPairs := make(map[Expression]Expression)
 		for key, value := range node.Pairs {
-			modifiedKey, _ := Modify(key, modifier).(Expression)
-			modifiedValue, _ := Modify(value, modifier).(Expression)
+			modifiedKey, _ := modifier(key).(Expression)
+			modifiedValue, _ := modifier(value).(Expression)
 			newPairs[modifiedKey] = modifiedValue
 		}
 		node.Pairs = newPairs
 	}
-	return modifier(node)
 }
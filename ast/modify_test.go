@@ -130,6 +130,34 @@ func TestModify(t *testing.T) {
 				},
 			},
 		},
+		{
+			&CallExpression{
+				Function:  one(),
+				Arguments: []Expression{one(), two()},
+			},
+			&CallExpression{
+				Function:  two(),
+				Arguments: []Expression{two(), two()},
+			},
+		},
+		{
+			&MacroLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&MacroLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
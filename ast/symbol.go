@@ -0,0 +1,55 @@
+// ast/symbol.go
+
+package ast
+
+// SymbolKind tells where the name an identifier refers to is bound.
+type SymbolKind int
+
+const (
+	LOCAL   SymbolKind = iota // in the function the identifier is in
+	CLOSURE                   // in an enclosing function
+	GLOBAL                    // at the top level of the program or session
+	BUILTIN                   // a builtin function or special form
+)
+
+func (k SymbolKind) String() string {
+	switch k {
+	case LOCAL:
+		return "local"
+	case CLOSURE:
+		return "closure"
+	case GLOBAL:
+		return "global"
+	case BUILTIN:
+		return "builtin"
+	default:
+		return "unknown"
+	}
+}
+
+// Symbol is the binding an identifier refers to.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+
+	// Def is the let name or parameter that binds the name, or nil for
+	// builtins and for globals bound outside the program.
+	Def *Identifier
+
+	// Depth is the number of scopes between the identifier and the scope
+	// binding the name, which is the number of environments to go out
+	// through when evaluating it. Slot is the index of the name in that
+	// scope, or -1 if the name is bound outside the program.
+	Depth int
+	Slot  int
+}
+
+// Scope is the program or a function or macro body, as worked out by the
+// resolver package. Its names are indexed by slot: the parameters come
+// first, then the names of its let statements in the order they first
+// appear. Slots maps each name back to its slot.
+type Scope struct {
+	Parent *Scope
+	Names  []string
+	Slots  map[string]int
+}
@@ -0,0 +1,66 @@
+//ast/token.go
+
+package ast
+
+import "monkey/token"
+
+// tokenOf returns the token node was created from. For infix, call and
+// index expressions that is the operator rather than the first token.
+func tokenOf(node Node) token.Token {
+	switch node := node.(type) {
+	case *LetStatement:
+		return node.Token
+	case *ReturnStatement:
+		return node.Token
+	case *ImportStatement:
+		return node.Token
+	case *ExpressionStatement:
+		return node.Token
+	case *BlockStatement:
+		return node.Token
+	case *Identifier:
+		return node.Token
+	case *IntegerLiteral:
+		return node.Token
+	case *StringLiteral:
+		return node.Token
+	case *Boolean:
+		return node.Token
+	case *NullLiteral:
+		return node.Token
+	case *PrefixExpression:
+		return node.Token
+	case *InfixExpression:
+		return node.Token
+	case *IfExpression:
+		return node.Token
+	case *FunctionLiteral:
+		return node.Token
+	case *MacroLiteral:
+		return node.Token
+	case *CallExpression:
+		return node.Token
+	case *ArrayLiteral:
+		return node.Token
+	case *IndexExpression:
+		return node.Token
+	case *HashLiteral:
+		return node.Token
+	case *NamedType:
+		return node.Token
+	case *ArrayType:
+		return node.Token
+	case *HashType:
+		return node.Token
+	case *FunctionType:
+		return node.Token
+	default:
+		return token.Token{}
+	}
+}
+
+// Position returns the line and column of the token node was created from.
+func Position(node Node) (line, column int) {
+	tok := tokenOf(node)
+	return tok.Line, tok.Column
+}
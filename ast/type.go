@@ -0,0 +1,91 @@
+// ast/type.go
+
+package ast
+
+import (
+	"bytes"
+	"monkey/token"
+	"strings"
+)
+
+// TypeExpression is a type annotation, written after the name of a let or
+// a parameter or after the parameters of a function literal. Annotations
+// are not children of the nodes they annotate: Walk and Modify do not
+// visit them, and the evaluator ignores them.
+type TypeExpression interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a type written as a name, such as int or any.
+type NamedType struct {
+	Token token.Token
+	Name  string
+}
+
+func (nt *NamedType) typeNode() {}
+
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+
+func (nt *NamedType) String() string { return nt.Name }
+
+// ArrayType is the type of arrays, written [element].
+type ArrayType struct {
+	Token   token.Token // the '[' token
+	Element TypeExpression
+}
+
+func (at *ArrayType) typeNode() {}
+
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+
+func (at *ArrayType) String() string {
+	return "[" + at.Element.String() + "]"
+}
+
+// HashType is the type of hashes, written {key: value}.
+type HashType struct {
+	Token token.Token // the '{' token
+	Key   TypeExpression
+	Value TypeExpression
+}
+
+func (ht *HashType) typeNode() {}
+
+func (ht *HashType) TokenLiteral() string { return ht.Token.Literal }
+
+func (ht *HashType) String() string {
+	return "{" + ht.Key.String() + ": " + ht.Value.String() + "}"
+}
+
+// FunctionType is the type of functions, written fn(parameters) -> result.
+type FunctionType struct {
+	Token      token.Token // the 'fn' token
+	Parameters []TypeExpression
+	Result     TypeExpression
+}
+
+func (ft *FunctionType) typeNode() {}
+
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+
+func (ft *FunctionType) String() string {
+	var out bytes.Buffer
+
+	params := make([]string, len(ft.Parameters))
+	for i, p := range ft.Parameters {
+		params[i] = p.String()
+	}
+
+	out.WriteString("fn(" + strings.Join(params, ", ") + ") -> ")
+	out.WriteString(ft.Result.String())
+	return out.String()
+}
+
+// annotated returns the name of ident followed by its annotation, if any.
+func annotated(ident *Identifier) string {
+	if ident.Type == nil {
+		return ident.String()
+	}
+	return ident.String() + ": " + ident.Type.String()
+}
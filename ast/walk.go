@@ -0,0 +1,14 @@
+//ast/walk.go
+
+package ast
+
+// Walk calls fn for node and then, if fn returns true, walks each child of
+// node in source order.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	for _, child := range children(node) {
+		Walk(child, fn)
+	}
+}
@@ -0,0 +1,45 @@
+// ast/walk_test.go
+
+package ast
+
+import (
+	"monkey/token"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "f"},
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "a"}},
+					Body: &BlockStatement{Statements: []Statement{
+						&ExpressionStatement{Expression: &Identifier{Value: "a"}},
+					}},
+				},
+			},
+			&ExpressionStatement{Expression: &HashLiteral{
+				Pairs: map[Expression]Expression{
+					&Identifier{Token: token.Token{Line: 2, Column: 8}, Value: "k2"}: &Identifier{Value: "v2"},
+					&Identifier{Token: token.Token{Line: 2, Column: 2}, Value: "k1"}: &Identifier{Value: "v1"},
+				},
+			}},
+		},
+	}
+
+	var visited []string
+	Walk(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			visited = append(visited, ident.Value)
+		}
+		_, isFunction := node.(*FunctionLiteral)
+		return !isFunction
+	})
+
+	expected := "f k1 v1 k2 v2"
+	if got := strings.Join(visited, " "); got != expected {
+		t.Errorf("wrong identifiers visited. want=%q, got=%q", expected, got)
+	}
+}
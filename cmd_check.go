@@ -0,0 +1,69 @@
+// cmd_check.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/types"
+	"os"
+)
+
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: monkey check [files...]\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			return exitError
+		}
+		return checkSource("<stdin>", src)
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			code = exitError
+			continue
+		}
+		if c := checkSource(path, src); c != exitOK {
+			code = c
+		}
+	}
+	return code
+}
+
+// checkSource prints the syntax or type errors in src, read from the file
+// named name. Unlike running it, checking a program reports type errors
+// whether or not it has annotations.
+func checkSource(name string, src []byte) int {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		for _, d := range p.Diagnostics() {
+			fmt.Fprintf(os.Stdout, "%s:%s\n", name, d)
+		}
+		return exitError
+	}
+
+	result := types.Check(program)
+	for _, d := range result.Diagnostics {
+		fmt.Fprintf(os.Stdout, "%s:%s\n", name, d)
+	}
+	if len(result.Diagnostics) > 0 {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,63 @@
+// cmd_cover.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/coverage"
+	"monkey/interpreter"
+	"os"
+)
+
+// coverFlags holds the flags that make a command measure which statements
+// and branches of the programs it runs are run.
+type coverFlags struct {
+	summary *bool
+	lcov    *string
+	profile *coverage.Profile
+}
+
+func newCoverFlags(fs *flag.FlagSet) *coverFlags {
+	return &coverFlags{
+		summary: fs.Bool("cover", false, "print how much of each file ran to stderr"),
+		lcov:    fs.String("coverprofile", "", "write an LCOV coverage report to `file`; implies -cover"),
+	}
+}
+
+// options returns the interpreter options that measure coverage, if it is
+// asked for. Every interpreter they configure shares one profile.
+func (c *coverFlags) options() []interpreter.Option {
+	if !*c.summary && *c.lcov == "" {
+		return nil
+	}
+	if c.profile == nil {
+		c.profile = coverage.New()
+	}
+	return []interpreter.Option{interpreter.WithCoverage(c.profile)}
+}
+
+// report prints the summary and writes the LCOV report, if coverage was
+// measured.
+func (c *coverFlags) report() int {
+	if c.profile == nil {
+		return exitOK
+	}
+	c.profile.WriteSummary(os.Stderr)
+	if *c.lcov == "" {
+		return exitOK
+	}
+
+	f, err := os.Create(*c.lcov)
+	if err == nil {
+		err = c.profile.WriteLCOV(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,86 @@
+// cmd_fmt.go
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/format"
+	"os"
+)
+
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: monkey fmt [flags] [files...]\n")
+		fs.PrintDefaults()
+	}
+	write := fs.Bool("w", false, "write the result to the files instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		if *write {
+			fmt.Fprintf(os.Stderr, "fmt: cannot use -w with standard input\n")
+			return exitUsage
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			return exitError
+		}
+		return formatSource("<stdin>", src, false)
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			code = exitError
+			continue
+		}
+		if c := formatSource(path, src, *write); c != exitOK {
+			code = c
+		}
+	}
+	return code
+}
+
+// formatSource formats src, read from the file named name, and either
+// prints it or writes it back to the file.
+func formatSource(name string, src []byte, write bool) int {
+	out, err := format.Source(src)
+	var syntaxErr *format.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		for _, d := range syntaxErr.Diagnostics {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, d)
+		}
+		return exitError
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		return exitError
+	}
+
+	if !write {
+		os.Stdout.Write(out)
+		return exitOK
+	}
+	if string(out) == string(src) {
+		return exitOK
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitError
+	}
+	if err := os.WriteFile(name, out, info.Mode().Perm()); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
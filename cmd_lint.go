@@ -0,0 +1,68 @@
+// cmd_lint.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/lint"
+	"os"
+	"strings"
+)
+
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: monkey lint [flags] [files...]\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nThe rules are:\n\n")
+		for _, r := range lint.Rules {
+			fmt.Fprintf(fs.Output(), "\t%-15s %s\n", r.ID, r.Description)
+		}
+	}
+	disable := fs.String("disable", "", "a comma-separated list of rules to leave out")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var disabled []string
+	if *disable != "" {
+		disabled = strings.Split(*disable, ",")
+	}
+
+	if fs.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			return exitError
+		}
+		return lintSource("<stdin>", src, disabled)
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			code = exitError
+			continue
+		}
+		if c := lintSource(path, src, disabled); c != exitOK {
+			code = c
+		}
+	}
+	return code
+}
+
+// lintSource prints the problems in src, read from the file named name.
+func lintSource(name string, src []byte, disabled []string) int {
+	diagnostics := lint.Source(src, disabled...)
+	for _, d := range diagnostics {
+		fmt.Fprintf(os.Stdout, "%s:%s\n", name, d)
+	}
+	if len(diagnostics) > 0 {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,27 @@
+// cmd_lsp.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/lsp"
+	"os"
+)
+
+func runLSP(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	// Messages go to the real standard output; anything macros being
+	// expanded print goes to standard error instead.
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	if err := lsp.NewServer(os.Stdin, out).Serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
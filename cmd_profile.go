@@ -0,0 +1,59 @@
+// cmd_profile.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/interpreter"
+	"monkey/profiler"
+	"os"
+	"time"
+)
+
+// profileFlags holds the flags that make a command profile the Monkey
+// functions of the programs it runs.
+type profileFlags struct {
+	file     *string
+	interval *time.Duration
+	profiler *profiler.Profiler
+}
+
+func newProfileFlags(fs *flag.FlagSet) *profileFlags {
+	return &profileFlags{
+		file:     fs.String("profile", "", "write a pprof profile of the Monkey functions called to `file`"),
+		interval: fs.Duration("profile-rate", 0, "sample the calls in progress at this interval rather than timing every call"),
+	}
+}
+
+// options returns the interpreter options that profile, if profiling is
+// asked for, and starts the profiler.
+func (p *profileFlags) options() []interpreter.Option {
+	if *p.file == "" {
+		return nil
+	}
+	p.profiler = profiler.New(*p.interval)
+	p.profiler.Start()
+	return []interpreter.Option{interpreter.WithProfiler(p.profiler)}
+}
+
+// write stops the profiler and writes the profile, if there is one.
+func (p *profileFlags) write() int {
+	if p.profiler == nil {
+		return exitOK
+	}
+	p.profiler.Stop()
+
+	f, err := os.Create(*p.file)
+	if err == nil {
+		err = p.profiler.WriteProfile(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
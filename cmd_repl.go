@@ -0,0 +1,34 @@
+// cmd_repl.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/repl"
+	"os"
+)
+
+func runREPL(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	traceMacros := fs.Bool("trace-macros", false, "log every macro expansion step to stderr")
+	noColor := fs.Bool("no-color", false, "do not highlight input")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var opts []repl.Option
+	if *traceMacros {
+		opts = append(opts, repl.WithMacroTrace(os.Stderr))
+	}
+	if *noColor {
+		opts = append(opts, repl.WithColor(false))
+	}
+
+	fmt.Printf("Welcome to the Monkey programming language REPL.\n")
+	fmt.Printf("Feel free to type in commands\n")
+
+	repl.Start(os.Stdin, os.Stdout, opts...)
+	fmt.Println("Goodbye!")
+	return exitOK
+}
@@ -0,0 +1,48 @@
+// cmd_rpc.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/rpc"
+	"net"
+	"os"
+	"strings"
+)
+
+func runRPC(args []string) int {
+	fs := flag.NewFlagSet("rpc", flag.ContinueOnError)
+	listen := fs.String("listen", "", "serve on `address` (host:port, or unix:path) instead of stdio")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	s := rpc.NewServer()
+	if *listen == "" {
+		// Responses go to the real standard output; anything the programs
+		// being evaluated print goes to standard error instead.
+		out := os.Stdout
+		os.Stdout = os.Stderr
+		if err := s.ServeConn(os.Stdin, out); err != nil {
+			fmt.Fprintf(os.Stderr, "rpc: %s\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	network, address := "tcp", *listen
+	if path, ok := strings.CutPrefix(*listen, "unix:"); ok {
+		network, address = "unix", path
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rpc: %s\n", err)
+		return exitError
+	}
+	if err := s.Serve(l); err != nil {
+		fmt.Fprintf(os.Stderr, "rpc: %s\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,170 @@
+// cmd_run.go
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/interpreter"
+	"monkey/object"
+	"os"
+	"path/filepath"
+)
+
+// interpreterFlags registers the flags shared by every command that runs
+// Monkey code.
+func interpreterFlags(fs *flag.FlagSet) func() []interpreter.Option {
+	traceMacros := fs.Bool("trace-macros", false, "log every macro expansion step to stderr")
+	optimize := fs.Bool("O", false, "optimize the program before running it")
+	dumpOptimized := fs.Bool("dump-optimized", false, "print the optimized program to stderr; implies -O")
+	path := fs.String("path", os.Getenv("MONKEYPATH"), "directories to search for imported modules, separated by "+string(filepath.ListSeparator))
+	return func() []interpreter.Option {
+		opts := []interpreter.Option{interpreter.WithWholeProgram(), interpreter.WithSearchPath(searchPath(*path)...)}
+		if *traceMacros {
+			opts = append(opts, interpreter.WithMacroTrace(os.Stderr))
+		}
+		switch {
+		case *dumpOptimized:
+			opts = append(opts, interpreter.WithOptimizer(os.Stderr))
+		case *optimize:
+			opts = append(opts, interpreter.WithOptimizer(nil))
+		}
+		return opts
+	}
+}
+
+// searchPath splits a list of directories such as $MONKEYPATH.
+func searchPath(list string) []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(list) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func runScript(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: monkey run [flags] <file> [args...]\n")
+		fs.PrintDefaults()
+	}
+	options := interpreterFlags(fs)
+	cover := newCoverFlags(fs)
+	profile := newProfileFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	path := fs.Arg(0)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitError
+	}
+
+	opts := append(options(), cover.options()...)
+	opts = append(opts, profile.options()...)
+	in := interpreter.New(append(opts, interpreter.WithFile(path))...)
+	in.Env.Set("ARGS", stringArray(fs.Args()[1:]))
+	_, err = in.Run(string(src))
+	if err != nil {
+		printError(path, err)
+	}
+	coverCode, profileCode := cover.report(), profile.write()
+	if coverCode != exitOK || profileCode != exitOK || err != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// runStdin runs the program read from standard input.
+func runStdin(args []string) int {
+	src, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitError
+	}
+
+	in := interpreter.New(interpreter.WithWholeProgram(), interpreter.WithSearchPath(searchPath(os.Getenv("MONKEYPATH"))...))
+	in.Env.Set("ARGS", stringArray(args))
+	if _, err := in.Run(string(src)); err != nil {
+		printError("<stdin>", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runEval(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: monkey eval [flags] -e '<expr>'\n")
+		fs.PrintDefaults()
+	}
+	expr := fs.String("e", "", "the expression to evaluate")
+	options := interpreterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *expr == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	in := interpreter.New(options()...)
+	result, err := in.Run(*expr)
+	if err != nil {
+		printError("<eval>", err)
+		return exitError
+	}
+	if result != nil && result.Type() != object.NULL_OBJ {
+		fmt.Println(result.Inspect())
+	}
+	return exitOK
+}
+
+// printError reports an error from running the program named name.
+func printError(name string, err error) {
+	var parseErr *interpreter.ParseError
+	var macroErr *interpreter.MacroError
+	var resolveErr *interpreter.ResolveError
+	var typeErr *interpreter.TypeError
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &parseErr):
+		for _, msg := range parseErr.Messages {
+			fmt.Fprintf(os.Stderr, "%s: parse error: %s\n", name, msg)
+		}
+	case errors.As(err, &macroErr):
+		for _, d := range macroErr.Diagnostics {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, d)
+		}
+	case errors.As(err, &resolveErr):
+		for _, d := range resolveErr.Diagnostics {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, d)
+		}
+	case errors.As(err, &typeErr):
+		for _, d := range typeErr.Diagnostics {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, d)
+		}
+	case errors.As(err, &runtimeErr) && runtimeErr.Err.Line != 0:
+		fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", name, runtimeErr.Err.Line, runtimeErr.Err.Column, err)
+	default:
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+	}
+}
+
+func stringArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, v := range values {
+		elements[i] = &object.String{Value: v}
+	}
+	return &object.Array{Elements: elements}
+}
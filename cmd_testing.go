@@ -0,0 +1,100 @@
+// cmd_testing.go
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/tester"
+	"os"
+	"time"
+)
+
+func runTest(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: monkey test [flags] [files, dirs or dir/...]\n")
+		fs.PrintDefaults()
+	}
+	options := interpreterFlags(fs)
+	cover := newCoverFlags(fs)
+	verbose := fs.Bool("v", false, "list every test, not only those that fail")
+	junit := fs.String("junit", "", "also write a JUnit XML report to `file`")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	files, err := tester.Find(patterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return exitError
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "monkey: no test files found\n")
+		return exitError
+	}
+
+	code := exitOK
+	var all []tester.Result
+	for _, file := range files {
+		results := tester.RunFile(file, append(options(), cover.options()...)...)
+		if !reportTests(file, results, *verbose) {
+			code = exitError
+		}
+		all = append(all, results...)
+	}
+
+	if *junit != "" {
+		if err := writeJUnit(*junit, all); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			return exitError
+		}
+	}
+	if c := cover.report(); c != exitOK {
+		return c
+	}
+	return code
+}
+
+// reportTests prints the results of the tests in file, as go test does,
+// and reports whether they all passed.
+func reportTests(file string, results []tester.Result, verbose bool) bool {
+	passed := true
+	var d time.Duration
+	for _, r := range results {
+		d += r.Duration
+		switch {
+		case !r.Passed() && r.Name == "":
+			passed = false
+			fmt.Printf("--- FAIL: %s\n    %s\n", r.Position(), r.Failure)
+		case !r.Passed():
+			passed = false
+			fmt.Printf("--- FAIL: %s (%s)\n    %s\n", r.Name, r.Position(), r.Failure)
+		case verbose:
+			fmt.Printf("--- PASS: %s (%s)\n", r.Name, r.Position())
+		}
+	}
+
+	if passed {
+		fmt.Printf("ok  \t%s\t%.3fs\n", file, d.Seconds())
+	} else {
+		fmt.Printf("FAIL\t%s\t%.3fs\n", file, d.Seconds())
+	}
+	return passed
+}
+
+func writeJUnit(path string, results []tester.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := tester.WriteJUnit(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
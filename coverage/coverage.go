@@ -0,0 +1,228 @@
+// coverage/coverage.go
+
+// Package coverage counts how often the statements and the branches of if
+// expressions of Monkey programs run, and reports the counts by source
+// position: as an LCOV tracefile, which coverage tools read, or as a
+// summary for the terminal.
+//
+// A program is added to a Profile before it runs, so that the statements
+// that never run are counted too. The evaluator then records each
+// statement and branch it runs in the Profile of its environment.
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"monkey/ast"
+	"sort"
+)
+
+// Profile holds the counts of the programs added to it.
+type Profile struct {
+	files      []*file
+	byName     map[string]*file
+	statements map[ast.Statement]*int
+	branches   map[*ast.IfExpression]*branches
+}
+
+// file holds what was counted of the programs from one source file.
+type file struct {
+	name       string
+	statements []statement
+	branches   []*branches
+}
+
+type statement struct {
+	line  int
+	count *int
+}
+
+// branches counts how often the condition of an if was evaluated, and how
+// often it chose the consequence and the alternative, which for an if
+// without an else is doing nothing.
+type branches struct {
+	line        int
+	evaluated   int
+	consequence int
+	alternative int
+}
+
+func New() *Profile {
+	return &Profile{
+		byName:     make(map[string]*file),
+		statements: make(map[ast.Statement]*int),
+		branches:   make(map[*ast.IfExpression]*branches),
+	}
+}
+
+// Add makes the statements and if expressions of program, read from the
+// file name, count. Code quoted by quote calls is left out, since it is
+// data rather than code that runs.
+func (p *Profile) Add(name string, program *ast.Program) {
+	f, ok := p.byName[name]
+	if !ok {
+		f = &file{name: name}
+		p.byName[name] = f
+		p.files = append(p.files, f)
+	}
+
+	ast.Walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			if node.Function.TokenLiteral() == "quote" {
+				return false
+			}
+		case *ast.LetStatement, *ast.ReturnStatement, *ast.ExpressionStatement, *ast.ImportStatement:
+			if _, ok := p.statements[node.(ast.Statement)]; ok {
+				break
+			}
+			line, _ := ast.Position(node)
+			count := new(int)
+			p.statements[node.(ast.Statement)] = count
+			f.statements = append(f.statements, statement{line: line, count: count})
+		case *ast.IfExpression:
+			if _, ok := p.branches[node]; ok {
+				break
+			}
+			line, _ := ast.Position(node)
+			b := &branches{line: line}
+			p.branches[node] = b
+			f.branches = append(f.branches, b)
+		}
+		return true
+	})
+}
+
+// Statement records that stmt ran, if it was added.
+func (p *Profile) Statement(stmt ast.Statement) {
+	if count, ok := p.statements[stmt]; ok {
+		*count++
+	}
+}
+
+// Branch records that the condition of node was evaluated and chose the
+// consequence if taken is set, or the alternative otherwise.
+func (p *Profile) Branch(node *ast.IfExpression, taken bool) {
+	b, ok := p.branches[node]
+	if !ok {
+		return
+	}
+	b.evaluated++
+	if taken {
+		b.consequence++
+	} else {
+		b.alternative++
+	}
+}
+
+// lines returns the count of each line of f that has statements: the
+// largest count of the statements that start on it.
+func (f *file) lines() (lines []int, counts map[int]int) {
+	counts = make(map[int]int)
+	for _, s := range f.statements {
+		if _, ok := counts[s.line]; !ok {
+			lines = append(lines, s.line)
+		}
+		counts[s.line] = max(counts[s.line], *s.count)
+	}
+	sort.Ints(lines)
+	return lines, counts
+}
+
+// WriteLCOV writes the counts to w as an LCOV tracefile, with a record for
+// each file.
+func (p *Profile) WriteLCOV(w io.Writer) error {
+	for _, f := range p.files {
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", f.name); err != nil {
+			return err
+		}
+
+		branchesHit := 0
+		for i, b := range f.branches {
+			for branch, count := range []int{b.consequence, b.alternative} {
+				taken := "-"
+				if b.evaluated > 0 {
+					taken = fmt.Sprint(count)
+				}
+				if count > 0 {
+					branchesHit++
+				}
+				if _, err := fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n", b.line, i, branch, taken); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", 2*len(f.branches), branchesHit); err != nil {
+			return err
+		}
+
+		lines, counts := f.lines()
+		linesHit := 0
+		for _, line := range lines {
+			if counts[line] > 0 {
+				linesHit++
+			}
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", line, counts[line]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines), linesHit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileSummary gives how much of a file ran.
+type FileSummary struct {
+	Name          string
+	Statements    int
+	StatementsRun int
+	Branches      int
+	BranchesTaken int
+}
+
+// Summary returns how much of each file ran, in the order the files were
+// added.
+func (p *Profile) Summary() []FileSummary {
+	var summaries []FileSummary
+	for _, f := range p.files {
+		s := FileSummary{Name: f.name, Statements: len(f.statements), Branches: 2 * len(f.branches)}
+		for _, stmt := range f.statements {
+			if *stmt.count > 0 {
+				s.StatementsRun++
+			}
+		}
+		for _, b := range f.branches {
+			if b.consequence > 0 {
+				s.BranchesTaken++
+			}
+			if b.alternative > 0 {
+				s.BranchesTaken++
+			}
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// WriteSummary writes a line to w for each file, giving the share of its
+// statements that ran and of its branches that were taken.
+func (p *Profile) WriteSummary(w io.Writer) error {
+	for _, s := range p.Summary() {
+		_, err := fmt.Fprintf(w, "%s: %s of statements (%d/%d), %s of branches (%d/%d)\n",
+			s.Name, percent(s.StatementsRun, s.Statements), s.StatementsRun, s.Statements,
+			percent(s.BranchesTaken, s.Branches), s.BranchesTaken, s.Branches)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
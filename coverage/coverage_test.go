@@ -0,0 +1,97 @@
+// coverage/coverage_test.go
+
+package coverage
+
+import (
+	"bytes"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestProfile(t *testing.T) {
+	program := parse(t, `let f = fn(x) {
+    if (x) { 1 } else { 2 }
+};
+f(true);
+quote(fn() { let y = 3; y });`)
+
+	p := New()
+	p.Add("a.mk", program)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	body := let.Value.(*ast.FunctionLiteral).Body
+	ifExp := body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+
+	// Run the program as the evaluator would.
+	p.Statement(program.Statements[0])
+	p.Statement(program.Statements[1])
+	p.Statement(body.Statements[0])
+	p.Branch(ifExp, true)
+	p.Statement(ifExp.Consequence.Statements[0])
+	p.Statement(program.Statements[2])
+	p.Statement(&ast.ExpressionStatement{}) // not added, so not counted
+
+	var lcov bytes.Buffer
+	if err := p.WriteLCOV(&lcov); err != nil {
+		t.Fatal(err)
+	}
+	expected := `TN:
+SF:a.mk
+BRDA:2,0,0,1
+BRDA:2,0,1,0
+BRF:2
+BRH:1
+DA:1,1
+DA:2,1
+DA:4,1
+DA:5,1
+LF:4
+LH:4
+end_of_record
+`
+	if lcov.String() != expected {
+		t.Errorf("wrong LCOV report.\nwant=%s\ngot=%s", expected, lcov.String())
+	}
+
+	var summary bytes.Buffer
+	p.WriteSummary(&summary)
+	expectedSummary := "a.mk: 83.3% of statements (5/6), 50.0% of branches (1/2)\n"
+	if summary.String() != expectedSummary {
+		t.Errorf("wrong summary. want=%q, got=%q", expectedSummary, summary.String())
+	}
+}
+
+func TestUnevaluatedBranches(t *testing.T) {
+	program := parse(t, "if (x) { 1 }")
+	p := New()
+	p.Add("b.mk", program)
+
+	var lcov bytes.Buffer
+	p.WriteLCOV(&lcov)
+	expected := `TN:
+SF:b.mk
+BRDA:1,0,0,-
+BRDA:1,0,1,-
+BRF:2
+BRH:0
+DA:1,0
+LF:1
+LH:0
+end_of_record
+`
+	if lcov.String() != expected {
+		t.Errorf("wrong LCOV report.\nwant=%s\ngot=%s", expected, lcov.String())
+	}
+}
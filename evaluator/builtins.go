@@ -4,9 +4,65 @@ package evaluator
 
 import (
 	"fmt"
+	"monkey/ast"
 	"monkey/object"
+	"sort"
+	"strings"
 )
 
+// specialForms are the names Eval treats specially when called, rather than
+// looking them up as functions.
+var specialForms = []string{"quote", "unquote", "unquote_splice", "macroexpand", "macroexpand_1", "assert_error"}
+
+// BuiltinNames returns the sorted names that are predefined in every
+// program: the builtin functions and the special forms.
+func BuiltinNames() []string {
+	names := append([]string{}, specialForms...)
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsBuiltin reports whether name is a builtin function or a special form.
+func IsBuiltin(name string) bool {
+	if _, ok := builtins[name]; ok {
+		return true
+	}
+	for _, form := range specialForms {
+		if form == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltinSignature returns how the builtin or special form name is called
+// and what it does.
+func BuiltinSignature(name string) (signature, doc string, ok bool) {
+	sig, ok := signatures[name]
+	return sig[0], sig[1], ok
+}
+
+var signatures = map[string][2]string{
+	"len":            {"len(value)", "Returns the number of bytes in a string or elements in an array."},
+	"first":          {"first(array)", "Returns the first element of array, or null if it is empty."},
+	"last":           {"last(array)", "Returns the last element of array, or null if it is empty."},
+	"rest":           {"rest(array)", "Returns a new array with every element of array but the first, or null if it is empty."},
+	"push":           {"push(array, value)", "Returns a new array with value appended to the elements of array."},
+	"puts":           {"puts(values...)", "Prints each value on a line of its own and returns null."},
+	"str":            {"str(value)", "Returns the display form of value as a string."},
+	"quote":          {"quote(expression)", "Returns expression unevaluated, as a quoted AST node."},
+	"unquote":        {"unquote(expression)", "Inside quote, evaluates expression and inserts the result."},
+	"unquote_splice": {"unquote_splice(expression)", "Inside quote, evaluates expression to an array and inserts its elements."},
+	"macroexpand":    {"macroexpand(quoted)", "Returns quoted with every macro call in it fully expanded."},
+	"macroexpand_1":  {"macroexpand_1(quoted)", "Returns quoted with the macro calls in it expanded one step."},
+	"assert":         {"assert(condition, message?)", "Fails with message unless condition is truthy."},
+	"assert_eq":      {"assert_eq(got, want)", "Fails unless got and want are equal, comparing arrays and hashes by their elements."},
+	"assert_error":   {"assert_error(expression, substring?)", "Fails unless expression fails with an error whose message contains substring, and returns the message."},
+}
+
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
@@ -106,4 +162,125 @@ var builtins = map[string]*object.Builtin{
 			return NULL
 		},
 	},
+	"str": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+	"assert": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			if isTruthy(args[0]) {
+				return NULL
+			}
+			if len(args) == 2 {
+				message, ok := args[1].(*object.String)
+				if !ok {
+					return newError("argument to `assert` must be STRING, got %s", args[1].Type())
+				}
+				return newError("assertion failed: %s", message.Value)
+			}
+			return newError("assertion failed")
+		},
+	},
+	"assert_eq": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if !objectsEqual(args[0], args[1]) {
+				return newError("assert_eq: got %s, want %s", args[0].Repr(), args[1].Repr())
+			}
+			return NULL
+		},
+	},
+}
+
+// assertError implements the assert_error form, which evaluates its first
+// argument in env and fails unless that fails.
+func assertError(args []ast.Expression, env *object.Environment) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	var substring string
+	if len(args) == 2 {
+		arg := Eval(args[1], env)
+		if isError(arg) {
+			return arg
+		}
+		str, ok := arg.(*object.String)
+		if !ok {
+			return newError("argument to `assert_error` must be STRING, got %s", arg.Type())
+		}
+		substring = str.Value
+	}
+
+	evaluated := Eval(args[0], env)
+	errObj, ok := evaluated.(*object.Error)
+	switch {
+	case env.Interrupted():
+		return newError("interrupted")
+	case !ok:
+		return newError("assert_error: expected an error, got %s", inspectResult(evaluated))
+	case !strings.Contains(errObj.Message, substring):
+		return newError("assert_error: got error %q, want one containing %q", errObj.Message, substring)
+	}
+	return &object.String{Value: errObj.Message}
+}
+
+func inspectResult(obj object.Object) string {
+	if obj == nil {
+		return "nothing"
+	}
+	return unwrapReturnValue(obj).Repr()
+}
+
+// objectsEqual reports whether a and b are the same value: equal integers,
+// strings or booleans, null, or arrays and hashes whose elements are equal.
+// Other values are only equal to themselves.
+func objectsEqual(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.Integer:
+		b, ok := b.(*object.Integer)
+		return ok && a.Value == b.Value
+	case *object.String:
+		b, ok := b.(*object.String)
+		return ok && a.Value == b.Value
+	case *object.Boolean:
+		b, ok := b.(*object.Boolean)
+		return ok && a.Value == b.Value
+	case *object.Null:
+		_, ok := b.(*object.Null)
+		return ok
+	case *object.Array:
+		b, ok := b.(*object.Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !objectsEqual(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		b, ok := b.(*object.Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !objectsEqual(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
 };
\ No newline at end of file
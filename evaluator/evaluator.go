@@ -15,6 +15,11 @@ var (
 )
 
 func Eval(node ast.Node, environment *object.Environment) object.Object {
+	environment.Step()
+	if environment.Interrupted() {
+		return newError("interrupted")
+	}
+
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, environment)
@@ -26,12 +31,14 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, environment)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPosition(evalPrefixExpression(node.Operator, right), node)
 	case *ast.InfixExpression:
 		left := Eval(node.Left, environment)
 		if isError(left) {
@@ -41,7 +48,7 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return withPosition(evalInfixExpression(node.Operator, left, right), node)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, environment)
 	case *ast.IfExpression:
@@ -49,6 +56,9 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 		if isError(condition) {
 			return condition
 		}
+		if observer := environment.Observer(); observer != nil {
+			observer.Branch(node, isTruthy(condition))
+		}
 		if isTruthy(condition) {
 			return Eval(node.Consequence, environment)
 		} else if node.Alternative != nil {
@@ -67,9 +77,28 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		environment.Set(node.Name.Value, val)
+		if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+			if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+				fn.Name = node.Name.Value
+			}
+		}
+		if sym := node.Name.Symbol; sym != nil && sym.Kind == ast.LOCAL {
+			environment.SetAt(sym.Slot, sym.Name, val)
+		} else {
+			environment.Set(node.Name.Value, val)
+		}
+	case *ast.ImportStatement:
+		module := evalImport(node, environment)
+		if isError(module) {
+			return withPosition(module, node)
+		}
+		if sym := node.Name.Symbol; sym != nil && sym.Kind == ast.LOCAL {
+			environment.SetAt(sym.Slot, sym.Name, module)
+		} else {
+			environment.Set(node.Name.Value, module)
+		}
 	case *ast.Identifier:
-		return evalIdentifier(node, environment)
+		return withPosition(evalIdentifier(node, environment), node)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
@@ -77,11 +106,20 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 			Parameters: params,
 			Body:       body,
 			Env:        environment,
+			Scope:      node.Scope,
 		}
 		return function
 	case *ast.CallExpression:
-		if node.Function.TokenLiteral() == "quote" {
+		switch node.Function.TokenLiteral() {
+		case "quote":
+			if len(node.Arguments) != 1 {
+				return withPosition(newError("wrong number of arguments. got=%d, want=1", len(node.Arguments)), node.Function)
+			}
 			return quote(node.Arguments[0], environment)
+		case "macroexpand", "macroexpand_1":
+			return macroExpand(node.Function.TokenLiteral(), node.Arguments, environment)
+		case "assert_error":
+			return withPosition(assertError(node.Arguments, environment), node.Function)
 		}
 		function := Eval(node.Function, environment)
 		if isError(function) {
@@ -92,7 +130,19 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		if fn, ok := function.(*object.Function); ok {
+			if err := checkArguments(fn, args); err != nil {
+				return withPosition(err, node.Function)
+			}
+			if environment.Observer() != nil {
+				return observedCall(fn, args, node, environment.Observer())
+			}
+		}
+		result := applyFunction(function, args)
+		if _, ok := function.(*object.Function); !ok {
+			return withPosition(result, node.Function)
+		}
+		return result
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, environment)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -108,7 +158,7 @@ func Eval(node ast.Node, environment *object.Environment) object.Object {
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return withPosition(evalIndexExpression(left, index), node)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, environment)
 	}
@@ -122,6 +172,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 			return evalArrayIndexExpression(left, index)
 		case left.Type() == object.HASH_OBJ:
 			return evalHashIndexExpression(left, index)
+		case left.Type() == object.MODULE_OBJ:
+			return evalModuleIndexExpression(left, index)
 		default:
 			return newError("index operator not supported: %s", left.Type())
 	}
@@ -151,9 +203,65 @@ func evalHashIndexExpression(left, index object.Object) object.Object {
 	return pair.Value
 }
 
+// withPosition gives obj the position of node if it is an error that does
+// not have one yet.
+func withPosition(obj object.Object, node ast.Node) object.Object {
+	if errObj, ok := obj.(*object.Error); ok && errObj.Line == 0 {
+		errObj.Line, errObj.Column = ast.Position(node)
+	}
+	return obj
+}
+
+func evalModuleIndexExpression(left, index object.Object) object.Object {
+	module := left.(*object.Module)
+	name, ok := index.(*object.String)
+	if !ok {
+		return newError("unusable as module export: %s", index.Type())
+	}
+
+	if val, ok := module.Exports[name.Value]; ok {
+		return val
+	}
+	if _, ok := module.Macros[name.Value]; ok {
+		return newError("macro %s of module %q can only be called", name.Value, module.Path)
+	}
+	return newError("module %q has no export %q", module.Path, name.Value)
+}
+
+// evalImport loads the module node imports with the importer of
+// environment.
+func evalImport(node *ast.ImportStatement, environment *object.Environment) object.Object {
+	importer, file, ok := environment.Importer()
+	if !ok {
+		return newError("import %q: modules cannot be imported here", node.Path.Value)
+	}
+
+	module, err := importer.Import(node.Path.Value, file)
+	if err != nil {
+		return newError("import %q: %s", node.Path.Value, err)
+	}
+	return module
+}
+
+// observedCall calls fn as call does, telling o when it enters and leaves.
+func observedCall(fn *object.Function, args []object.Object, call *ast.CallExpression, o object.Observer) object.Object {
+	line, _ := ast.Position(call.Function)
+	o.Enter(fn, line)
+	defer o.Exit()
+	return applyFunction(fn, args)
+}
+
+// Apply calls fn, a function or builtin, with args and returns the result.
+func Apply(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args)
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 		case *object.Function:
+			if err := checkArguments(fn, args); err != nil {
+				return err
+			}
 			extendedEnv := extendFunctionEnv(fn, args)
 			evaluated := Eval(fn.Body, extendedEnv)
 			return unwrapReturnValue(evaluated)
@@ -164,7 +272,27 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 	}
 }
 
+// checkArguments returns an error if there are not as many args as fn has
+// parameters, and nil otherwise.
+func checkArguments(fn *object.Function, args []object.Object) object.Object {
+	if len(args) != len(fn.Parameters) {
+		return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+	}
+	return nil
+}
+
+// extendFunctionEnv binds the parameters of fn to args, which checkArguments
+// has found to be as many.
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	if fn.Scope != nil {
+		// The parameters take the first slots.
+		env := object.NewSlotEnvironment(fn.Env, fn.Scope)
+		for i, param := range fn.Parameters {
+			env.SetAt(i, param.Value, args[i])
+		}
+		return env
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for i, param := range fn.Parameters {
@@ -193,6 +321,14 @@ func evalExpressions(expression []ast.Expression, environment *object.Environmen
 }
 
 func evalIdentifier(node *ast.Identifier, environment *object.Environment) object.Object {
+	// A resolved local is found in its slot, unless the let binding it
+	// has not run yet; then it is looked up by name, as when unresolved.
+	if sym := node.Symbol; sym != nil && (sym.Kind == ast.LOCAL || sym.Kind == ast.CLOSURE) {
+		if val, ok := environment.GetAt(sym.Depth, sym.Slot); ok {
+			return val
+		}
+	}
+
 	val, ok := environment.Get(node.Value)
 	if ok {
 		return val
@@ -207,7 +343,11 @@ func evalIdentifier(node *ast.Identifier, environment *object.Environment) objec
 func evalBlockStatement(node *ast.BlockStatement, environment *object.Environment) object.Object {
 	var result object.Object
 
+	observer := environment.Observer()
 	for _, stmt := range node.Statements {
+		if observer != nil {
+			observer.Statement(stmt)
+		}
 		result = Eval(stmt, environment)
 
 		if result != nil {
@@ -321,7 +461,11 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 
 func evalProgram(program *ast.Program, environment *object.Environment) object.Object {
 	var result object.Object
+	observer := environment.Observer()
 	for _, stmt := range program.Statements {
+		if observer != nil {
+			observer.Statement(stmt)
+		}
 		result = Eval(stmt, environment)
 
 		switch result := result.(type) {
@@ -363,7 +507,7 @@ func evalHashLiteral(node *ast.HashLiteral, environment *object.Environment) obj
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return withPosition(newError("unusable as hash key: %s", key.Type()), keyNode)
 		}
 
 		value := Eval(valueNode, environment)
@@ -3,10 +3,14 @@
 package evaluator
 
 import (
+	"errors"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/resolver"
+	"strings"
 	"testing"
+	"time"
 )
 
 func testIntegerObject(t *testing.T, evaluated object.Object, expected int64) {
@@ -246,6 +250,14 @@ if (10 > 1) {
 			`{"name": "Monkey"}[fn(x) { x; }]`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"let f = fn(x) { x }; f()",
+			"wrong number of arguments. got=0, want=1",
+		},
+		{
+			"fn(x) { x }(1, 2)",
+			"wrong number of arguments. got=2, want=1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -324,6 +336,70 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+// testEvalResolved evaluates input after resolving its identifiers, so that
+// function calls use slot environments.
+func testEvalResolved(input string) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	r := &resolver.Resolver{Builtins: IsBuiltin}
+	r.Resolve(program)
+	return Eval(program, object.NewEnvironment())
+}
+
+func TestResolvedEvaluation(t *testing.T) {
+	inputs := []string{
+		"let f = fn(a, b) { let c = a * b; c + a }; f(3, 4)",
+		"let newAdder = fn(x) { fn(y) { x + y } }; let addTwo = newAdder(2); addTwo(3)",
+		"let f = fn(x) { fn(y) { fn(z) { x + y + z } } }; f(1)(2)(3)",
+		"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)",
+		"let f = fn(x) { let x = x + 1; let x = x * 2; x }; f(3)",
+		"let f = fn(c) { if (c) { let y = 1 }; y }; f(true)",
+		"let y = 10; let f = fn(c) { if (c) { let y = 1 }; y }; [f(true), f(false)]",
+		"let f = fn() { let g = fn() { h() }; let h = fn() { 5 }; g() }; f()",
+		"let len = fn(x) { 0 }; let f = fn() { len(\"abc\") }; f()",
+		"let f = fn(arr) { let sum = fn(i, acc) { if (i == len(arr)) { acc } else { sum(i + 1, acc + arr[i]) } }; sum(0, 0) }; f([1, 2, 3, 4])",
+		"let f = fn(k) { {k: fn() { k }}[k]() }; f(\"a\")",
+		"let f = fn(x) { if (x > 1) { return x * 10; } x }; [f(1), f(2)]",
+		"let f = fn(x) { quote(unquote(x) + y) }; f(1)",
+		"let f = fn() { undefined }; f()",
+		"let f = fn(a, b) { a }; f(1)",
+		"let f = fn(a) { fn(b) { a + b } }; f(1)()",
+	}
+
+	for _, input := range inputs {
+		want := testEval(input)
+		got := testEvalResolved(input)
+		if got.Inspect() != want.Inspect() {
+			t.Errorf("resolved evaluation of %q differs. want=%s, got=%s", input, want.Inspect(), got.Inspect())
+		}
+	}
+
+	fn, ok := testEvalResolved("fn(a) { let b = a; b }").(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function.")
+	}
+	if fn.Scope == nil || strings.Join(fn.Scope.Names, " ") != "a b" {
+		t.Errorf("function has wrong scope. got=%+v", fn.Scope)
+	}
+}
+
+func benchmarkFib(b *testing.B, resolve bool) {
+	program := parser.New(lexer.New(
+		"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(20)",
+	)).ParseProgram()
+	if resolve {
+		r := &resolver.Resolver{Builtins: IsBuiltin}
+		r.Resolve(program)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
+
+func BenchmarkFib(b *testing.B)         { benchmarkFib(b, false) }
+func BenchmarkFibResolved(b *testing.B) { benchmarkFib(b, true) }
+
 func TestClosures(t *testing.T) {
 	input := `
 let newAdder = fn(x) {
@@ -563,4 +639,229 @@ func TestHashIndexExpressions(t *testing.T) {
             testNullObject(t, evaluated)
         }
     }
-}
\ No newline at end of file
+}
+func TestInterrupt(t *testing.T) {
+	l := lexer.New(`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) + f(n - 1) } }; f(40);`)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	environment := object.NewEnvironment()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		environment.Interrupt()
+	}()
+
+	evaluated := Eval(program, environment)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "interrupted" {
+		t.Errorf("wrong error message. expected=%q, got=%q", "interrupted", errObj.Message)
+	}
+
+	environment.ClearInterrupt()
+	testIntegerObject(t, Eval(testParseProgram("f(3)"), environment), 0)
+}
+
+func TestStrBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`str("a\tb")`, "a\tb"},
+		{`str(12)`, "12"},
+		{`str(["a", 1])`, `["a", 1]`},
+		{`str(null)`, "null"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval(`str(1, 2)`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "wrong number of arguments. got=2, want=1" {
+		t.Errorf("wrong result for str(1, 2). got=%s", evaluated.Inspect())
+	}
+}
+
+func TestReprRoundTrip(t *testing.T) {
+	inputs := []string{
+		`"plain"`,
+		`"say \"hi\"\n\ttabbed \\ done"`,
+		`["a", ["b\n"], 1, true, null]`,
+		`{"k\"ey": ["v"], 2: false}`,
+	}
+
+	for _, input := range inputs {
+		first := testEval(input)
+		second := testEval(first.Repr())
+		if first.Repr() != second.Repr() {
+			t.Errorf("repr of %s does not round-trip. first=%s, second=%s", input, first.Repr(), second.Repr())
+		}
+	}
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func TestBuiltinSignatures(t *testing.T) {
+	for _, name := range BuiltinNames() {
+		signature, doc, ok := BuiltinSignature(name)
+		if !ok || !strings.HasPrefix(signature, name+"(") || doc == "" {
+			t.Errorf("missing or malformed signature for %s: %q, %q", name, signature, doc)
+		}
+	}
+}
+
+// fakeImporter serves modules from a map, by path.
+type fakeImporter map[string]*object.Module
+
+func (f fakeImporter) Import(path, from string) (*object.Module, error) {
+	if module, ok := f[path]; ok {
+		return module, nil
+	}
+	return nil, errors.New("module not found")
+}
+
+func TestImportStatements(t *testing.T) {
+	importer := fakeImporter{
+		"lib.mk": {
+			Path:    "lib.mk",
+			Exports: map[string]object.Object{"answer": &object.Integer{Value: 42}},
+			Macros:  map[string]*object.Macro{"m": {}},
+		},
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "lib.mk" as lib; lib["answer"]`, 42},
+		{`let f = fn() { import "lib.mk" as lib; lib["answer"] }; f()`, 42},
+		{`import "lib.mk" as lib; lib`, `<module "lib.mk">`},
+		{`import "lib.mk" as lib; lib["other"]`, `module "lib.mk" has no export "other"`},
+		{`import "lib.mk" as lib; lib["m"]`, `macro m of module "lib.mk" can only be called`},
+		{`import "lib.mk" as lib; lib[1]`, "unusable as module export: INTEGER"},
+		{`import "nope.mk" as lib;`, `import "nope.mk": module not found`},
+	}
+
+	for _, tt := range tests {
+		environment := object.NewEnvironment()
+		environment.SetImporter(importer, "main.mk")
+		evaluated := Eval(testParseProgram(tt.input), environment)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("wrong error message for %q. want=%q, got=%q", tt.input, expected, errObj.Message)
+				}
+			} else if evaluated == nil || evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q. want=%q, got=%v", tt.input, expected, evaluated)
+			}
+		}
+	}
+
+	evaluated := testEval(`import "lib.mk" as lib;`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != `import "lib.mk": modules cannot be imported here` {
+		t.Errorf("expected an error without an importer. got=%v", evaluated)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the error message, or empty if there is none
+		line     int
+		column   int
+	}{
+		{`assert(1 < 2)`, "", 0, 0},
+		{`assert(1 > 2)`, "assertion failed", 1, 1},
+		{`assert(null, "no value")`, "assertion failed: no value", 1, 1},
+		{`assert(false, 1)`, "argument to `assert` must be STRING, got INTEGER", 1, 1},
+		{`assert_eq([1, {"a": [2]}], [1, {"a": [2]}])`, "", 0, 0},
+		{`assert_eq("a", "a"); assert_eq(null, null)`, "", 0, 0},
+		{"let f = fn() { 1 };\nassert_eq(f, f)", "", 0, 0},
+		{`let f = fn(x) {
+  assert_eq(x, [1, "2"])
+}; f([1, 2])`, `assert_eq: got [1, 2], want [1, "2"]`, 2, 3},
+		{`assert_eq({1: 2}, {1: 2, 3: 4})`, "assert_eq: got {1: 2}, want {1: 2, 3: 4}", 1, 1},
+		{`assert_error(1 + true)`, "", 0, 0},
+		{`assert_error(len(1), "not supported")`, "", 0, 0},
+		{`assert_error(len(1), "nope")`,
+			`assert_error: got error "argument to ` + "`len`" + ` not supported, got INTEGER", want one containing "nope"`, 1, 1},
+		{` assert_error(1 + 1)`, "assert_error: expected an error, got 2", 1, 2},
+		{`len(1, 2)`, "wrong number of arguments. got=2, want=1", 1, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("unexpected error for %q: %s", tt.input, errObj.Message)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected || errObj.Line != tt.line || errObj.Column != tt.column {
+			t.Errorf("wrong error for %q. want=%d:%d: %s, got=%d:%d: %s",
+				tt.input, tt.line, tt.column, tt.expected, errObj.Line, errObj.Column, errObj.Message)
+		}
+	}
+
+	evaluated := testEval(`assert_error(len(1))`)
+	if str, ok := evaluated.(*object.String); !ok || str.Value != "argument to `len` not supported, got INTEGER" {
+		t.Errorf("expected assert_error to return the message. got=%v", evaluated)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		line     int
+		column   int
+	}{
+		{"1;\n  5 + true", "type mismatch: INTEGER + BOOLEAN", 2, 5},
+		{`"a" - "b"`, "unknown operator: STRING - STRING", 1, 5},
+		{"let x = 1;\n-true", "unknown operator: -BOOLEAN", 2, 1},
+		{"  foobar", "identifier not found: foobar", 1, 3},
+		{"1[0]", "index operator not supported: INTEGER", 1, 2},
+		{`{"a": 1}[fn(x) { x }]`, "unusable as hash key: FUNCTION", 1, 9},
+		{`{fn(x) { x }: 1}`, "unusable as hash key: FUNCTION", 1, 2},
+		{"let one = 1;\none()", "not a function: INTEGER", 2, 1},
+		{"let f = fn() {\n  1 + true\n};\nf() + 1", "type mismatch: INTEGER + BOOLEAN", 2, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected || errObj.Line != tt.line || errObj.Column != tt.column {
+			t.Errorf("wrong error for %q. want=%d:%d: %s, got=%d:%d: %s",
+				tt.input, tt.line, tt.column, tt.expected, errObj.Line, errObj.Column, errObj.Message)
+		}
+	}
+}
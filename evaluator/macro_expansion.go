@@ -3,24 +3,41 @@
 package evaluator
 
 import (
+	"fmt"
+	"io"
 	"monkey/ast"
 	"monkey/object"
 )
 
+// maxExpansionDepth bounds how many times the output of a macro may itself be
+// expanded again, so that a macro expanding to a call of itself terminates.
+const maxExpansionDepth = 100
+
 func DefineMacros(program *ast.Program, environment *object.Environment) {
+	program.Statements = defineMacros(program.Statements, environment)
+}
+
+// defineMacros adds every macro definition found in statements to
+// environment and returns the statements with those definitions removed.
+// The modules imported by statements are bound in environment too, so that
+// their macros can be called; the imports themselves are kept.
+func defineMacros(statements []ast.Statement, environment *object.Environment) []ast.Statement {
 	definitions := []int{}
 
-	for i, stmt := range program.Statements {
+	for i, stmt := range statements {
 		if isMacroDefinition(stmt) {
 			addMacro(stmt, environment)
 			definitions = append(definitions, i)
+		} else if importStatement, ok := stmt.(*ast.ImportStatement); ok {
+			addModule(importStatement, environment)
 		}
 	}
 
 	for i := len(definitions) - 1; i >= 0; i-- {
 		definitionIndex := definitions[i]
-		program.Statements = append(program.Statements[:definitionIndex], program.Statements[definitionIndex+1:]...)
+		statements = append(statements[:definitionIndex], statements[definitionIndex+1:]...)
 	}
+	return statements
 }
 
 func addMacro(stmt ast.Statement, environment *object.Environment) {
@@ -28,12 +45,20 @@ func addMacro(stmt ast.Statement, environment *object.Environment) {
 	macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
 	macro := &object.Macro{
 		Parameters: macroLiteral.Parameters,
-		Env:       environment,
-		Body:      macroLiteral.Body,
+		Env:        environment,
+		Body:       macroLiteral.Body,
 	}
 	environment.Set(letStatement.Name.Value, macro)
 }
 
+// addModule binds the module stmt imports in environment. A module that
+// fails to load is left unbound; evaluating the import reports why.
+func addModule(stmt *ast.ImportStatement, environment *object.Environment) {
+	if module, ok := evalImport(stmt, environment).(*object.Module); ok {
+		environment.Set(stmt.Name.Value, module)
+	}
+}
+
 func isMacroDefinition(stmt ast.Statement) bool {
 	letStatement, ok := stmt.(*ast.LetStatement)
 	if !ok {
@@ -44,74 +69,290 @@ func isMacroDefinition(stmt ast.Statement) bool {
 	return ok
 }
 
+// ExpandMacros replaces every macro call in program with the AST its macro
+// returns. Macros defined inside a block are only visible within that block,
+// and the result of an expansion is expanded again until no macro calls
+// remain. Calls that cannot be expanded are left in place and reported as
+// diagnostics.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, []Diagnostic) {
+	expander := &Expander{Env: env}
+	return expander.Expand(program)
+}
 
-func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
-    return ast.Modify(program, func(node ast.Node) ast.Node {
-        callExpression, ok := node.(*ast.CallExpression)
-        if !ok {
-            return node
-        }
+// Diagnostic describes a macro call that could not be expanded.
+type Diagnostic struct {
+	Macro   string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: macro %s: %s", d.Line, d.Column, d.Macro, d.Message)
+}
 
-        macro, ok := isMacroCall(callExpression, env)
-        if !ok {
-            return node
-        }
+// Expander expands the macro calls of a program against the macros defined
+// in Env.
+type Expander struct {
+	Env *object.Environment
 
-        args := quoteArgs(callExpression)
-        evalEnv := extendMacroEnv(macro, args)
+	// Trace, if set, receives a line for every macro call that is expanded,
+	// giving the macro name, the call and what it expanded to.
+	Trace io.Writer
 
-        evaluated := Eval(macro.Body, evalEnv)
+	// Recover, if set, reports a macro whose body panics as a diagnostic at
+	// its call instead of letting the panic through.
+	Recover bool
 
-        quote, ok := evaluated.(*object.Quote)
-        if !ok {
-            panic("we only support returning AST-nodes from macros")
-        }
+	diagnostics []Diagnostic
+}
 
-        return quote.Node
-    })
+// Expand expands every macro call in node, as ExpandMacros does.
+func (e *Expander) Expand(node ast.Node) (ast.Node, []Diagnostic) {
+	e.diagnostics = nil
+	node = e.expand(node, e.Env, 0, nil)
+	return node, e.diagnostics
+}
+
+// ExpandOnce expands node a single time if it is a macro call, without
+// expanding the result or any macro calls nested inside it.
+func (e *Expander) ExpandOnce(node ast.Node) (ast.Node, []Diagnostic) {
+	e.diagnostics = nil
+
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return node, nil
+	}
+
+	macro, ok := isMacroCall(callExpression, e.Env)
+	if !ok {
+		return node, nil
+	}
+
+	if expanded, ok := e.expandCall(callExpression, macro, callExpression); ok {
+		node = expanded
+	}
+	return node, e.diagnostics
+}
+
+// expand expands the macro calls in node. site is the call written in the
+// program whose expansion produced node, if any; errors are reported there
+// rather than inside the macro that generated the failing call.
+func (e *Expander) expand(node ast.Node, env *object.Environment, depth int, site *ast.CallExpression) ast.Node {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		env = object.NewEnclosedEnvironment(env)
+		node.Statements = defineMacros(node.Statements, env)
+	case *ast.MacroLiteral:
+		// Macro bodies are expanded when their result is, not before.
+		return node
+	case *ast.CallExpression:
+		if node.Function.TokenLiteral() == "quote" {
+			return node
+		}
+	}
+
+	ast.ModifyChildren(node, func(child ast.Node) ast.Node {
+		return e.expand(child, env, depth, site)
+	})
+
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return node
+	}
+
+	macro, ok := isMacroCall(callExpression, env)
+	if !ok {
+		return node
+	}
+
+	if site == nil {
+		site = callExpression
+	}
+
+	if depth >= maxExpansionDepth {
+		e.errorf(callExpression, site, "expansion exceeded maximum depth of %d", maxExpansionDepth)
+		return node
+	}
+
+	expanded, ok := e.expandCall(callExpression, macro, site)
+	if !ok {
+		return node
+	}
+	return e.expand(expanded, env, depth+1, site)
+}
+
+func (e *Expander) expandCall(callExpression *ast.CallExpression, macro *object.Macro, site *ast.CallExpression) (node ast.Node, ok bool) {
+	if e.Recover {
+		defer func() {
+			if r := recover(); r != nil {
+				e.errorf(callExpression, site, "panic: %v", r)
+				node, ok = nil, false
+			}
+		}()
+	}
+
+	if len(callExpression.Arguments) != len(macro.Parameters) {
+		e.errorf(callExpression, site, "wrong number of arguments. got=%d, want=%d",
+			len(callExpression.Arguments), len(macro.Parameters))
+		return nil, false
+	}
+
+	// Keep the call as written for the trace; expansion may modify it.
+	call := callExpression.String()
+
+	args := quoteArgs(callExpression)
+	evalEnv := extendMacroEnv(macro, args)
+
+	evaluated := Eval(macro.Body, evalEnv)
+	if isError(evaluated) {
+		e.errorf(callExpression, site, "%s", evaluated.(*object.Error).Message)
+		return nil, false
+	}
+
+	evaluated = unwrapReturnValue(evaluated)
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		e.errorf(callExpression, site, "must return a quoted AST node, got %s", typeOf(evaluated))
+		return nil, false
+	}
+
+	if e.Trace != nil {
+		fmt.Fprintf(e.Trace, "macro %s: %s => %s\n",
+			callExpression.Function.String(), call, quote.Node.String())
+	}
+	return quote.Node, true
+}
+
+// errorf reports that the macro called by call failed, at the position of
+// the call site it was expanded from.
+func (e *Expander) errorf(call, site *ast.CallExpression, format string, a ...any) {
+	token := site.Token
+	if identifier, ok := site.Function.(*ast.Identifier); ok {
+		token = identifier.Token
+	}
+
+	e.diagnostics = append(e.diagnostics, Diagnostic{
+		Macro:   call.Function.String(),
+		Line:    token.Line,
+		Column:  token.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
+
+// macroExpand implements the macroexpand and macroexpand_1 forms, which
+// expand the quoted node they are given against the macros visible to env.
+func macroExpand(name string, args []ast.Expression, env *object.Environment) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	arg := Eval(args[0], env)
+	if isError(arg) {
+		return arg
+	}
+
+	quoted, ok := arg.(*object.Quote)
+	if !ok {
+		return newError("argument to `%s` must be QUOTE, got %s", name, arg.Type())
+	}
+
+	macroEnv, ok := env.MacroEnv()
+	if !ok {
+		macroEnv = object.NewEnvironment()
+	}
+	expander := &Expander{Env: macroEnv}
+
+	var node ast.Node
+	var diagnostics []Diagnostic
+	if name == "macroexpand_1" {
+		node, diagnostics = expander.ExpandOnce(ast.Copy(quoted.Node))
+	} else {
+		node, diagnostics = expander.Expand(ast.Copy(quoted.Node))
+	}
+	if len(diagnostics) > 0 {
+		return newError("%s", diagnostics[0])
+	}
+	return &object.Quote{Node: node}
 }
 
 func isMacroCall(
-    exp *ast.CallExpression,
-    env *object.Environment,
+	exp *ast.CallExpression,
+	env *object.Environment,
 ) (*object.Macro, bool) {
-    identifier, ok := exp.Function.(*ast.Identifier)
-    if !ok {
-        return nil, false
-    }
+	if index, ok := exp.Function.(*ast.IndexExpression); ok {
+		return isModuleMacro(index, env)
+	}
+
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		return nil, false
+	}
+
+	return macro, true
+}
 
-    obj, ok := env.Get(identifier.Value)
-    if !ok {
-        return nil, false
-    }
+// isModuleMacro returns the macro index names if it is written
+// module["name"] with module an imported module that exports the macro.
+func isModuleMacro(index *ast.IndexExpression, env *object.Environment) (*object.Macro, bool) {
+	identifier, ok := index.Left.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	name, ok := index.Index.(*ast.StringLiteral)
+	if !ok {
+		return nil, false
+	}
 
-    macro, ok := obj.(*object.Macro)
-    if !ok {
-        return nil, false
-    }
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return nil, false
+	}
 
-    return macro, true
+	macro, ok := module.Macros[name.Value]
+	return macro, ok
 }
 
 func quoteArgs(exp *ast.CallExpression) []*object.Quote {
-    args := []*object.Quote{}
+	args := []*object.Quote{}
 
-    for _, a := range exp.Arguments {
-        args = append(args, &object.Quote{Node: a})
-    }
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
 
-    return args
+	return args
 }
 
 func extendMacroEnv(
-    macro *object.Macro,
-    args []*object.Quote,
+	macro *object.Macro,
+	args []*object.Quote,
 ) *object.Environment {
-    extended := object.NewEnclosedEnvironment(macro.Env)
+	extended := object.NewEnclosedEnvironment(macro.Env)
 
-    for paramIdx, param := range macro.Parameters {
-        extended.Set(param.Value, args[paramIdx])
-    }
+	for paramIdx, param := range macro.Parameters {
+		extended.Set(param.Value, args[paramIdx])
+	}
 
-    return extended
-}
\ No newline at end of file
+	return extended
+}
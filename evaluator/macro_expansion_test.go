@@ -3,6 +3,7 @@
 package evaluator
 
 import (
+	"bytes"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/object"
@@ -106,6 +107,45 @@ func TestExpandMacros(t *testing.T) {
             `,
 			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
 		},
+		{
+			`
+            let double = macro(x) { quote(unquote(x) * 2); };
+
+            puts(double(1), [double(2)]);
+            `,
+			`puts(1 * 2, [2 * 2])`,
+		},
+		{
+			`
+            let one = macro() { quote(1); };
+            let two = macro() { quote(one() + one()); };
+
+            two();
+            `,
+			`1 + 1`,
+		},
+		{
+			`
+            let f = fn() {
+                let inner = macro(x) { quote(unquote(x) + 1); };
+                inner(1);
+            };
+            inner(2);
+            `,
+			`let f = fn() { 1 + 1; }; inner(2);`,
+		},
+		{
+			`
+            let m = macro() { quote(1); };
+            if (true) {
+                let m = macro() { quote(2); };
+                m();
+            } else {
+                m();
+            }
+            `,
+			`if (true) { 2 } else { 1 }`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,7 +154,10 @@ func TestExpandMacros(t *testing.T) {
 
 		env := object.NewEnvironment()
 		DefineMacros(program, env)
-		expanded := ExpandMacros(program, env)
+		expanded, diagnostics := ExpandMacros(program, env)
+		if len(diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diagnostics)
+		}
 
 		if expanded.String() != expected.String() {
 			t.Errorf("not equal. want=%q, got=%q",
@@ -122,3 +165,183 @@ func TestExpandMacros(t *testing.T) {
 		}
 	}
 }
+
+func TestExpandMacrosDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			`
+let forever = macro() { quote(forever()); };
+forever();
+`,
+			[]string{"3:1: macro forever: expansion exceeded maximum depth of 100"},
+		},
+		{
+			`
+let two = macro(a, b) { quote(unquote(a) + unquote(b)); };
+two(1);
+two(1, 2, 3);
+two(1, 2);
+`,
+			[]string{
+				"3:1: macro two: wrong number of arguments. got=1, want=2",
+				"4:1: macro two: wrong number of arguments. got=3, want=2",
+			},
+		},
+		{
+			`
+let number = macro() { 1 };
+let broken = macro() { 1 + true };
+let a = number();
+  broken();
+`,
+			[]string{
+				"4:9: macro number: must return a quoted AST node, got INTEGER",
+				"5:3: macro broken: type mismatch: INTEGER + BOOLEAN",
+			},
+		},
+		{
+			`
+let splice = macro(f) { quote(unquote(f)(unquote_splice(missing))); };
+splice(puts);
+`,
+			[]string{"3:1: macro splice: identifier not found: missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+
+		_, diagnostics := ExpandMacros(program, env)
+		if len(diagnostics) != len(tt.expected) {
+			t.Fatalf("wrong number of diagnostics. want=%d, got=%d (%v)",
+				len(tt.expected), len(diagnostics), diagnostics)
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("diagnostics[%d] wrong. want=%q, got=%q", i, tt.expected[i], d.String())
+			}
+		}
+	}
+}
+
+func TestMacroExpand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+            let one = macro() { quote(1); };
+            let two = macro() { quote(one() + one()); };
+            macroexpand(quote(two()));
+            `,
+			`(1 + 1)`,
+		},
+		{
+			`
+            let one = macro() { quote(1); };
+            let two = macro() { quote(one() + one()); };
+            macroexpand_1(quote(two()));
+            `,
+			`(one() + one())`,
+		},
+		{
+			`
+            let double = macro(x) { quote(unquote(x) * 2); };
+            let q = quote(puts(double(3)));
+            macroexpand(q);
+            `,
+			`puts((3 * 2))`,
+		},
+		{
+			`macroexpand(quote(f(1)))`,
+			`f(1)`,
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		env := object.NewEnvironment()
+		macroEnv := object.NewEnvironment()
+		env.SetMacroEnv(macroEnv)
+
+		DefineMacros(program, macroEnv)
+		expanded, _ := ExpandMacros(program, macroEnv)
+		evaluated := Eval(expanded, env)
+
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Fatalf("expected *object.Quote. got=%T (%+v)", evaluated, evaluated)
+		}
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. want=%q, got=%q", tt.expected, quote.Node.String())
+		}
+	}
+}
+
+func TestMacroExpandErrors(t *testing.T) {
+	evaluated := testEval(`macroexpand(1)`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := "argument to `macroexpand` must be QUOTE, got INTEGER"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestExpanderTrace(t *testing.T) {
+	input := `
+    let one = macro() { quote(1); };
+    let inc = macro(x) { quote(unquote(x) + one()); };
+    inc(2);
+    `
+
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+
+	var trace bytes.Buffer
+	expander := &Expander{Env: env, Trace: &trace}
+	if _, diagnostics := expander.Expand(program); len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+
+	expected := "macro inc: inc(2) => (2 + one())\n" +
+		"macro one: one() => 1\n"
+	if trace.String() != expected {
+		t.Errorf("wrong trace. want=%q, got=%q", expected, trace.String())
+	}
+}
+
+// panicWriter panics on every write.
+type panicWriter struct{}
+
+func (panicWriter) Write(p []byte) (int, error) {
+	panic("write to panicWriter")
+}
+
+func TestExpanderRecover(t *testing.T) {
+	input := `
+    let one = macro() { quote(1); };
+    one();
+    `
+
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+
+	// Tracing to a panicWriter stands in for a macro that panics.
+	expander := &Expander{Env: env, Trace: panicWriter{}, Recover: true}
+	_, diagnostics := expander.Expand(program)
+	if len(diagnostics) != 1 || diagnostics[0].Message != "panic: write to panicWriter" {
+		t.Errorf("wrong diagnostics. got=%v", diagnostics)
+	}
+}
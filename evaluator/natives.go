@@ -0,0 +1,139 @@
+// evaluator/natives.go
+
+package evaluator
+
+import "monkey/object"
+
+// Natives are the functions over arrays that the standard library is built
+// on. They are written in Go so that they take time linear in the length of
+// the array and do not recurse once per element, as Monkey code walking an
+// array with rest and push would. They are the exports of the module
+// std/native.
+var Natives = map[string]*object.Builtin{
+	"map": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, f, err := arrayAndFunction("map", args)
+			if err != nil {
+				return err
+			}
+			result := make([]object.Object, len(arr.Elements))
+			for i, el := range arr.Elements {
+				result[i] = applyFunction(f, []object.Object{el})
+				if isError(result[i]) {
+					return result[i]
+				}
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	"filter": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, keep, err := arrayAndFunction("filter", args)
+			if err != nil {
+				return err
+			}
+			result := []object.Object{}
+			for _, el := range arr.Elements {
+				kept := applyFunction(keep, []object.Object{el})
+				if isError(kept) {
+					return kept
+				}
+				if isTruthy(kept) {
+					result = append(result, el)
+				}
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	"reduce": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+			arr, f, err := arrayAndFunction("reduce", []object.Object{args[0], args[2]})
+			if err != nil {
+				return err
+			}
+			acc := args[1]
+			for _, el := range arr.Elements {
+				acc = applyFunction(f, []object.Object{acc, el})
+				if isError(acc) {
+					return acc
+				}
+			}
+			return acc
+		},
+	},
+	"any": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, pred, err := arrayAndFunction("any", args)
+			if err != nil {
+				return err
+			}
+			for _, el := range arr.Elements {
+				result := applyFunction(pred, []object.Object{el})
+				if isError(result) {
+					return result
+				}
+				if isTruthy(result) {
+					return TRUE
+				}
+			}
+			return FALSE
+		},
+	},
+	"reverse": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `reverse` must be ARRAY, got %s", args[0].Type())
+			}
+			n := len(arr.Elements)
+			result := make([]object.Object, n)
+			for i, el := range arr.Elements {
+				result[n-1-i] = el
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	"range": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			start, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to `range` must be INTEGER, got %s", args[0].Type())
+			}
+			end, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("argument to `range` must be INTEGER, got %s", args[1].Type())
+			}
+			result := []object.Object{}
+			for i := start.Value; i < end.Value; i++ {
+				result = append(result, &object.Integer{Value: i})
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+}
+
+// arrayAndFunction checks that args, given to the native name, are an
+// array and a function to call on its elements.
+func arrayAndFunction(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin:
+		return arr, args[1], nil
+	}
+	return nil, nil, newError("argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+}
@@ -9,16 +9,44 @@ import (
 	"monkey/token"
 )
 
+// quote returns node as a quoted AST node, with its unquote and
+// unquote_splice calls replaced by what their arguments evaluate to. If one
+// of them fails, or evaluates to a value that cannot be turned back into
+// code, the error is returned instead.
 func quote(node ast.Node, env *object.Environment) object.Object {
-	node = evalUnquoteCalls(node, env)
+	u := &unquoter{env: env}
+	node = u.evalUnquoteCalls(ast.Copy(node))
+	if u.err != nil {
+		return u.err
+	}
 	return &object.Quote{
 		Node: node,
 	}
 }
 
-func evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
+// unquoter evaluates the unquote calls of a quoted node, keeping the first
+// error; once there is one, the rest of the node is left as it is.
+type unquoter struct {
+	env *object.Environment
+	err *object.Error
+}
+
+func (u *unquoter) evalUnquoteCalls(quoted ast.Node) ast.Node {
 	return ast.Modify(quoted, func(node ast.Node) ast.Node {
-		if !isUnquoteCall(node) {
+		if u.err != nil {
+			return node
+		}
+
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			node.Arguments = u.spliceExpressions(node.Arguments)
+		case *ast.ArrayLiteral:
+			node.Elements = u.spliceExpressions(node.Elements)
+		case *ast.BlockStatement:
+			node.Statements = u.spliceStatements(node.Statements)
+		}
+
+		if u.err != nil || !isUnquoteCall(node) {
 			return node
 		}
 
@@ -29,8 +57,11 @@ func evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
 		if len(call.Arguments) != 1 {
 			return node
 		}
-		unquoted := Eval(call.Arguments[0], env)
-		return convertObjectToASTNode(unquoted)
+		converted := u.convert(Eval(call.Arguments[0], u.env), "unquote")
+		if converted == nil {
+			return node
+		}
+		return converted
 	})
 }
 
@@ -42,14 +73,139 @@ func isUnquoteCall(node ast.Node) bool {
 	return callExpression.Function.TokenLiteral() == "unquote"
 }
 
-func convertObjectToASTNode(obj object.Object) ast.Node {
+func isUnquoteSpliceCall(node ast.Node) bool {
+	callExpression, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	return callExpression.Function.TokenLiteral() == "unquote_splice" &&
+		len(callExpression.Arguments) == 1
+}
+
+// spliceExpressions replaces every unquote_splice(...) element of list with
+// the nodes its argument evaluates to.
+func (u *unquoter) spliceExpressions(list []ast.Expression) []ast.Expression {
+	result := []ast.Expression{}
+	for _, exp := range list {
+		if !isUnquoteSpliceCall(exp) {
+			result = append(result, exp)
+			continue
+		}
+		for _, node := range u.evalUnquoteSplice(exp.(*ast.CallExpression)) {
+			exp := nodeToExpression(node)
+			if exp == nil {
+				u.fail("unquote_splice: cannot splice a statement into an expression")
+				return list
+			}
+			result = append(result, exp)
+		}
+		if u.err != nil {
+			return list
+		}
+	}
+	return result
+}
+
+// spliceStatements is spliceExpressions for the statements of a block, where
+// the unquote_splice(...) call appears as an expression statement.
+func (u *unquoter) spliceStatements(list []ast.Statement) []ast.Statement {
+	result := []ast.Statement{}
+	for _, stmt := range list {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok || !isUnquoteSpliceCall(es.Expression) {
+			result = append(result, stmt)
+			continue
+		}
+		for _, node := range u.evalUnquoteSplice(es.Expression.(*ast.CallExpression)) {
+			if stmt := nodeToStatement(node); stmt != nil {
+				result = append(result, stmt)
+			}
+		}
+		if u.err != nil {
+			return list
+		}
+	}
+	return result
+}
+
+// evalUnquoteSplice returns the nodes the argument of call evaluates to:
+// one for each element of an array, or one for any other value.
+func (u *unquoter) evalUnquoteSplice(call *ast.CallExpression) []ast.Node {
+	spliced := Eval(call.Arguments[0], u.env)
+
+	array, ok := spliced.(*object.Array)
+	if !ok {
+		if node := u.convert(spliced, "unquote_splice"); node != nil {
+			return []ast.Node{node}
+		}
+		return nil
+	}
+
+	nodes := []ast.Node{}
+	for _, elem := range array.Elements {
+		node := u.convert(elem, "unquote_splice")
+		if node == nil {
+			return nil
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// convert turns obj, the result of the unquote call named by form, back into
+// code. It returns nil, having recorded the error, if obj is an error or a
+// value that has no code.
+func (u *unquoter) convert(obj object.Object, form string) ast.Node {
+	if errObj, ok := obj.(*object.Error); ok {
+		u.err = errObj
+		return nil
+	}
+	node, bad := convertObjectToASTNode(obj)
+	if node == nil {
+		u.fail("%s: cannot convert %s to code", form, typeOf(bad))
+	}
+	return node
+}
+
+func (u *unquoter) fail(format string, a ...any) {
+	if u.err == nil {
+		u.err = newError(format, a...)
+	}
+}
+
+func nodeToExpression(node ast.Node) ast.Expression {
+	switch node := node.(type) {
+	case ast.Expression:
+		return node
+	case *ast.ExpressionStatement:
+		return node.Expression
+	default:
+		return nil
+	}
+}
+
+func nodeToStatement(node ast.Node) ast.Statement {
+	switch node := node.(type) {
+	case ast.Statement:
+		return node
+	case ast.Expression:
+		t := token.Token{Literal: node.TokenLiteral()}
+		return &ast.ExpressionStatement{Token: t, Expression: node}
+	default:
+		return nil
+	}
+}
+
+// convertObjectToASTNode returns the code for obj. If obj, or a value it
+// holds, cannot be turned into code, it returns nil and that value.
+func convertObjectToASTNode(obj object.Object) (ast.Node, object.Object) {
 	switch obj := obj.(type) {
 	case *object.Integer:
 		t := token.Token{
 			Type:    token.INT,
 			Literal: fmt.Sprintf("%d", obj.Value),
 		}
-		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, nil
 	case *object.Boolean:
 		var t token.Token
 		if obj.Value {
@@ -57,10 +213,55 @@ func convertObjectToASTNode(obj object.Object) ast.Node {
 		} else {
 			t = token.Token{Type: token.FALSE, Literal: "false"}
 		}
-		return &ast.Boolean{Token: t, Value: obj.Value}
+		return &ast.Boolean{Token: t, Value: obj.Value}, nil
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, nil
+	case *object.Null:
+		t := token.Token{Type: token.NULL, Literal: "null"}
+		return &ast.NullLiteral{Token: t}, nil
+	case *object.Array:
+		t := token.Token{Type: token.LBRACKET, Literal: "["}
+		elements := []ast.Expression{}
+		for _, elem := range obj.Elements {
+			exp, bad := convertToExpression(elem)
+			if exp == nil {
+				return nil, bad
+			}
+			elements = append(elements, exp)
+		}
+		return &ast.ArrayLiteral{Token: t, Elements: elements}, nil
+	case *object.Hash:
+		t := token.Token{Type: token.LBRACE, Literal: "{"}
+		pairs := make(map[ast.Expression]ast.Expression)
+		for _, pair := range obj.Pairs {
+			key, bad := convertToExpression(pair.Key)
+			if key == nil {
+				return nil, bad
+			}
+			value, bad := convertToExpression(pair.Value)
+			if value == nil {
+				return nil, bad
+			}
+			pairs[key] = value
+		}
+		return &ast.HashLiteral{Token: t, Pairs: pairs}, nil
 	case *object.Quote:
-		return obj.Node // if it's already an AST node, return it directly
+		return obj.Node, nil // if it's already an AST node, return it directly
 	default:
-		return nil
+		return nil, obj
+	}
+}
+
+// convertToExpression is convertObjectToASTNode for values held in arrays
+// and hashes, which must be expressions.
+func convertToExpression(obj object.Object) (ast.Expression, object.Object) {
+	node, bad := convertObjectToASTNode(obj)
+	if node == nil {
+		return nil, bad
+	}
+	if exp := nodeToExpression(node); exp != nil {
+		return exp, nil
 	}
+	return nil, obj
 }
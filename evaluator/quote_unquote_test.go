@@ -94,6 +94,26 @@ func TestQuoteUnquote(t *testing.T) {
             quote(unquote(4 + 4) + unquote(quotedInfixExpression))`,
 			`(8 + (4 + 4))`,
 		},
+		{
+			`quote(unquote("str"))`,
+			`str`,
+		},
+		{
+			`quote(unquote([1, 1 + 1, "three"]))`,
+			`[1, 2, three]`,
+		},
+		{
+			`quote(unquote({"one": 1}))`,
+			`{one: 1}`,
+		},
+		{
+			`quote(unquote(null))`,
+			`null`,
+		},
+		{
+			`quote(unquote(if (false) { 1 }))`,
+			`null`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,3 +134,95 @@ func TestQuoteUnquote(t *testing.T) {
 		}
 	}
 }
+
+func TestQuoteUnquoteSplice(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`quote(f(1, unquote_splice([quote(a), quote(b + c)])))`,
+			`f(1, a, (b + c))`,
+		},
+		{
+			`let args = [2, 3];
+            quote([1, unquote_splice(args), 4])`,
+			`[1, 2, 3, 4]`,
+		},
+		{
+			`quote(f(unquote_splice([])))`,
+			`f()`,
+		},
+		{
+			`let body = [quote(puts(x)), quote(x + 1)];
+            quote(fn(x) { unquote_splice(body); })`,
+			`fn(x) puts(x)(x + 1)`,
+		},
+		{
+			`quote([unquote_splice(5)])`,
+			`[5]`,
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Fatalf("expected *object.Quote. got=%T (%+v)",
+				evaluated, evaluated)
+		}
+
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. got=%q, want=%q",
+				quote.Node.String(), tt.expected)
+		}
+	}
+}
+
+func TestQuoteUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`quote(unquote(1 + true))`,
+			`type mismatch: INTEGER + BOOLEAN`,
+		},
+		{
+			`quote(unquote(fn(x) { x }))`,
+			`unquote: cannot convert FUNCTION to code`,
+		},
+		{
+			`quote(unquote([1, len]))`,
+			`unquote: cannot convert BUILTIN to code`,
+		},
+		{
+			`quote(f(unquote_splice(missing)))`,
+			`identifier not found: missing`,
+		},
+		{
+			`quote([unquote_splice([1, fn() { 2 }])])`,
+			`unquote_splice: cannot convert FUNCTION to code`,
+		},
+		{
+			`quote()`,
+			`wrong number of arguments. got=0, want=1`,
+		},
+		{
+			`quote(1, 2)`,
+			`wrong number of arguments. got=2, want=1`,
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("expected *object.Error for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
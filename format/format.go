@@ -0,0 +1,342 @@
+// format/format.go
+
+// Package format prints Monkey programs in a canonical layout: four-space
+// indentation, one statement per line ending in a semicolon, single spaces
+// around infix operators and after commas, and only the parentheses the
+// parser's precedence rules need. Comments and single blank lines between
+// statements are kept. Expressions are always printed on one line, so a
+// comment inside one that spans lines, such as between the elements of an
+// array, is moved to the line after the statement that holds it.
+// Formatting formatted code does not change it.
+package format
+
+import (
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"monkey/utils"
+	"strconv"
+	"strings"
+)
+
+const indentation = "    "
+
+// atom is the precedence of expressions that never need parentheses, such
+// as identifiers, literals and if expressions.
+const atom = parser.INDEX + 1
+
+// SyntaxError is returned when the source to format does not parse.
+type SyntaxError struct {
+	Diagnostics []parser.Diagnostic
+}
+
+func (e *SyntaxError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Source formats the Monkey program src. It returns a *SyntaxError if src
+// does not parse.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		return nil, &SyntaxError{Diagnostics: p.Diagnostics()}
+	}
+
+	pr := &printer{lines: strings.Split(string(src), "\n"), comments: l.Comments()}
+	pr.program(program)
+	return []byte(pr.out.String()), nil
+}
+
+// Node writes node to w in the canonical layout. There are no comments to
+// keep, since the AST has none.
+func Node(w io.Writer, node ast.Node) error {
+	pr := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.program(node)
+	case ast.Statement:
+		pr.statement(node)
+	case ast.Expression:
+		pr.expression(node)
+	default:
+		return fmt.Errorf("format: unexpected node %T", node)
+	}
+	_, err := io.WriteString(w, pr.out.String())
+	return err
+}
+
+// printer accumulates formatted code. lines and comments come from the
+// source being formatted, if any; comments are removed as they are printed.
+type printer struct {
+	out    strings.Builder
+	indent int
+
+	lines    []string
+	comments []token.Token
+
+	// blockStart is set until the first line inside a block or at the top
+	// of the program is printed; no blank line is kept before it.
+	blockStart bool
+}
+
+func (p *printer) program(program *ast.Program) {
+	p.blockStart = true
+	p.statements(program.Statements, -1)
+}
+
+// statements prints statements one per line, with the comments that come
+// before each, those that follow one on the line it ends, and those before
+// the line end, or all that are left if end is -1.
+func (p *printer) statements(statements []ast.Statement, end int) {
+	for _, stmt := range statements {
+		line := statementLine(stmt)
+		p.commentsBefore(line)
+		p.startLine(line)
+		p.statement(stmt)
+		if len(p.comments) > 0 && p.comments[0].Line == endLine(stmt) {
+			p.out.WriteString(" " + p.comment())
+		}
+		p.out.WriteString("\n")
+	}
+	p.commentsBefore(end)
+}
+
+// commentsBefore prints the pending comments before line, each on a line of
+// its own, or all of them if line is -1.
+func (p *printer) commentsBefore(line int) {
+	for len(p.comments) > 0 && (line == -1 || p.comments[0].Line < line) {
+		p.startLine(p.comments[0].Line)
+		p.out.WriteString(p.comment() + "\n")
+	}
+}
+
+// comment removes and returns the next pending comment.
+func (p *printer) comment() string {
+	c := p.comments[0]
+	p.comments = p.comments[1:]
+	return strings.TrimRight(c.Literal, " \t\r")
+}
+
+// startLine indents the next line, which holds what was on line of the
+// source, preceded by a blank line if there was one in the source.
+func (p *printer) startLine(line int) {
+	if !p.blockStart && line >= 2 && line-2 < len(p.lines) && strings.TrimSpace(p.lines[line-2]) == "" {
+		p.out.WriteString("\n")
+	}
+	p.blockStart = false
+	p.out.WriteString(strings.Repeat(indentation, p.indent))
+}
+
+func statementLine(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Line
+	case *ast.ImportStatement:
+		return stmt.Token.Line
+	case *ast.ReturnStatement:
+		return stmt.Token.Line
+	case *ast.ExpressionStatement:
+		return stmt.Token.Line
+	default:
+		return 0
+	}
+}
+
+// endLine returns the last source line of stmt.
+func endLine(stmt ast.Statement) int {
+	last := statementLine(stmt)
+	ast.Walk(stmt, func(node ast.Node) bool {
+		var line int
+		switch node := node.(type) {
+		case *ast.BlockStatement:
+			line = node.Rbrace.Line
+		case *ast.Identifier:
+			line = node.Token.Line
+		case *ast.IntegerLiteral:
+			line = node.Token.Line
+		case *ast.StringLiteral:
+			line = node.Token.Line
+		case *ast.Boolean:
+			line = node.Token.Line
+		case *ast.NullLiteral:
+			line = node.Token.Line
+		}
+		last = max(last, line)
+		return true
+	})
+	return last
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt.Exported {
+			p.out.WriteString("export ")
+		}
+		p.out.WriteString("let " + annotated(stmt.Name) + " = ")
+		p.expression(stmt.Value)
+		p.out.WriteString(";")
+	case *ast.ImportStatement:
+		p.out.WriteString("import " + utils.Quote(stmt.Path.Value) + " as " + stmt.Name.Value + ";")
+	case *ast.ReturnStatement:
+		p.out.WriteString("return ")
+		p.expression(stmt.ReturnValue)
+		p.out.WriteString(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+		if _, ok := stmt.Expression.(*ast.IfExpression); !ok {
+			p.out.WriteString(";")
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+// block prints a block with its statements indented on lines of their own,
+// or as {} if it is empty.
+func (p *printer) block(block *ast.BlockStatement) {
+	end := block.Rbrace.Line
+	hasComments := len(p.comments) > 0 && p.comments[0].Line < end
+	if len(block.Statements) == 0 && !hasComments {
+		p.out.WriteString("{}")
+		return
+	}
+
+	p.out.WriteString("{\n")
+	p.indent++
+	p.blockStart = true
+	p.statements(block.Statements, end)
+	p.indent--
+	p.out.WriteString(strings.Repeat(indentation, p.indent) + "}")
+}
+
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(e.Operator))
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	default:
+		return atom
+	}
+}
+
+// operand prints e, in parentheses if it binds less tightly than min.
+func (p *printer) operand(e ast.Expression, min int) {
+	if precedence(e) < min {
+		p.out.WriteString("(")
+		p.expression(e)
+		p.out.WriteString(")")
+		return
+	}
+	p.expression(e)
+}
+
+func (p *printer) expression(e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.out.WriteString(e.Value)
+	case *ast.IntegerLiteral:
+		p.out.WriteString(strconv.FormatInt(e.Value, 10))
+	case *ast.StringLiteral:
+		p.out.WriteString(utils.Quote(e.Value))
+	case *ast.Boolean:
+		p.out.WriteString(strconv.FormatBool(e.Value))
+	case *ast.NullLiteral:
+		p.out.WriteString("null")
+	case *ast.PrefixExpression:
+		p.out.WriteString(e.Operator)
+		p.operand(e.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		prec := precedence(e)
+		p.operand(e.Left, prec)
+		p.out.WriteString(" " + e.Operator + " ")
+		// Operators are left-associative, so an operand on the right
+		// needs parentheses even when it binds just as tightly.
+		p.operand(e.Right, prec+1)
+	case *ast.IfExpression:
+		p.out.WriteString("if (")
+		p.expression(e.Condition)
+		p.out.WriteString(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.out.WriteString(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.out.WriteString("fn")
+		p.parameters(e.Parameters)
+		if e.ReturnType != nil {
+			p.out.WriteString("-> " + e.ReturnType.String() + " ")
+		}
+		p.block(e.Body)
+	case *ast.MacroLiteral:
+		p.out.WriteString("macro")
+		p.parameters(e.Parameters)
+		p.block(e.Body)
+	case *ast.CallExpression:
+		p.operand(e.Function, parser.CALL)
+		p.out.WriteString("(")
+		p.expressions(e.Arguments)
+		p.out.WriteString(")")
+	case *ast.IndexExpression:
+		p.operand(e.Left, parser.CALL)
+		p.out.WriteString("[")
+		p.expression(e.Index)
+		p.out.WriteString("]")
+	case *ast.ArrayLiteral:
+		p.out.WriteString("[")
+		p.expressions(e.Elements)
+		p.out.WriteString("]")
+	case *ast.HashLiteral:
+		p.out.WriteString("{")
+		for i, key := range e.Keys() {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.expression(key)
+			p.out.WriteString(": ")
+			p.expression(e.Pairs[key])
+		}
+		p.out.WriteString("}")
+	}
+}
+
+func (p *printer) expressions(list []ast.Expression) {
+	for i, e := range list {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.expression(e)
+	}
+}
+
+func (p *printer) parameters(params []*ast.Identifier) {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = annotated(param)
+	}
+	p.out.WriteString("(" + strings.Join(names, ", ") + ") ")
+}
+
+// annotated returns the name of a let or parameter followed by its type
+// annotation, if it has one.
+func annotated(ident *ast.Identifier) string {
+	if ident.Type == nil {
+		return ident.Value
+	}
+	return ident.Value + ": " + ident.Type.String()
+}
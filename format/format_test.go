@@ -0,0 +1,164 @@
+// format/format_test.go
+
+package format
+
+import (
+	"errors"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1+2*3", "let x = 1 + 2 * 3;\n"},
+		{"(1 + 2) * 3;", "(1 + 2) * 3;\n"},
+		{"((1 * 2)) + (3);", "1 * 2 + 3;\n"},
+		{"1 - (2 - 3); (1 - 2) - 3;", "1 - (2 - 3);\n1 - 2 - 3;\n"},
+		{"-(1 + 2); !(-x); (-x)[0]; (f(x))(y)", "-(1 + 2);\n!-x;\n(-x)[0];\nf(x)(y);\n"},
+		{"(-x)[0] == (a < b); (a == b) < c", "(-x)[0] == a < b;\n(a == b) < c;\n"},
+		{"(a + b)[0]; (a[0])[1]; (fn(x) { x })(1)", "(a + b)[0];\na[0][1];\nfn(x) {\n    x;\n}(1);\n"},
+		{`let s="a\"b\n"`, "let s = \"a\\\"b\\n\";\n"},
+		{"[1,2 , 3];{1:2, \"a\" :[]};{}", "[1, 2, 3];\n{1: 2, \"a\": []};\n{};\n"},
+		{"let f = fn(a,b){return a+b}", "let f = fn(a, b) {\n    return a + b;\n};\n"},
+		{"fn(){}", "fn() {};\n"},
+		{"let n:int=1; fn(a:[string],b){a}", "let n: int = 1;\nfn(a: [string], b) {\n    a;\n};\n"},
+		{"let f:fn(int,bool)->{string:int}=fn(x:int)->int{x}", "let f: fn(int, bool) -> {string: int} = fn(x: int) -> int {\n    x;\n};\n"},
+		{
+			"if(x>1){x}else{if (y) { y } }",
+			"if (x > 1) {\n    x;\n} else {\n    if (y) {\n        y;\n    }\n}\n",
+		},
+		{"let y = if (x) { 1 };", "let y = if (x) {\n    1;\n};\n"},
+		{"let m = macro(a) { quote(unquote(a)) }", "let m = macro(a) {\n    quote(unquote(a));\n};\n"},
+		{`import"lib.mk"as lib
+export let f=fn(x){lib["g"](x)}`, "import \"lib.mk\" as lib;\nexport let f = fn(x) {\n    lib[\"g\"](x);\n};\n"},
+		{"let a = null; true; false", "let a = null;\ntrue;\nfalse;\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;", "let a = 1;\n\nlet b = 2;\nlet c = 3;\n"},
+		{"\n\nlet f = fn() {\n\n  1;\n\n  2\n\n};", "let f = fn() {\n    1;\n\n    2;\n};\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) returned %v", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Source(%q) wrong.\nwant=%q\ngot= %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	input := `// Adds things.
+let add = fn(a, b) { // the arguments
+  // the sum
+  a + b // trailing
+  // before the brace
+};
+
+// at the end
+let x = 1;   // one
+// last`
+
+	expected := `// Adds things.
+let add = fn(a, b) {
+    // the arguments
+    // the sum
+    a + b; // trailing
+    // before the brace
+};
+
+// at the end
+let x = 1; // one
+// last
+`
+
+	got, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source returned %v", err)
+	}
+	if string(got) != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot= %q", expected, got)
+	}
+}
+
+func TestSourceCommentsInExpressions(t *testing.T) {
+	input := `let a = [1, // one
+  2];
+puts(a, // the array
+  len(a));`
+
+	expected := `let a = [1, 2];
+// one
+puts(a, len(a));
+// the array
+`
+
+	got, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source returned %v", err)
+	}
+	if string(got) != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot= %q", expected, got)
+	}
+}
+
+func TestSourceIsIdempotent(t *testing.T) {
+	inputs := []string{
+		"let a = 1 + (2 * 3) - -(4); // a\n\n\nlet b = fn(x) { if (x) { x } else { // none\n} };",
+		"let m = macro(a, b) { quote(unquote(b) - unquote(a)) };\nm(1, 2)[0]\n// end",
+		"{\"a\": fn() { return (1 < 2) == (3 > 4) }}",
+		"let f = fn() {\n  // only a comment\n};\nf()",
+	}
+
+	for _, input := range inputs {
+		once, err := Source([]byte(input))
+		if err != nil {
+			t.Fatalf("Source(%q) returned %v", input, err)
+		}
+		twice, err := Source(once)
+		if err != nil {
+			t.Fatalf("Source(%q) returned %v", once, err)
+		}
+		if string(once) != string(twice) {
+			t.Errorf("formatting is not idempotent.\nonce= %q\ntwice=%q", once, twice)
+		}
+
+		// The formatted program means the same as the input.
+		want := parser.New(lexer.New(input)).ParseProgram().String()
+		got := parser.New(lexer.New(string(once))).ParseProgram().String()
+		if want != got {
+			t.Errorf("formatting changed the program.\nwant=%s\ngot= %s", want, got)
+		}
+	}
+}
+
+func TestSourceSyntaxError(t *testing.T) {
+	_, err := Source([]byte("let x 1;"))
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("err is not a *SyntaxError. got=%T (%v)", err, err)
+	}
+	if err.Error() != "1:7: expected next token to be =, got INT instead" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
+
+func TestNode(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(x) { x * (2 + 3) }; // gone")).ParseProgram()
+
+	var out strings.Builder
+	if err := Node(&out, program); err != nil {
+		t.Fatalf("Node returned %v", err)
+	}
+	expected := "let f = fn(x) {\n    x * (2 + 3);\n};\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot= %q", expected, out.String())
+	}
+}
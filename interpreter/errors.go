@@ -0,0 +1,70 @@
+// interpreter/errors.go
+
+package interpreter
+
+import (
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/resolver"
+	"monkey/types"
+	"strings"
+)
+
+// ParseError holds the errors reported by the parser.
+type ParseError struct {
+	Messages []string
+}
+
+func (e *ParseError) Error() string {
+	return strings.Join(e.Messages, "\n")
+}
+
+// MacroError holds the diagnostics reported while expanding macros.
+type MacroError struct {
+	Diagnostics []evaluator.Diagnostic
+}
+
+func (e *MacroError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ResolveError holds the identifiers that would not be found when the
+// program ran.
+type ResolveError struct {
+	Diagnostics []resolver.Diagnostic
+}
+
+func (e *ResolveError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// TypeError holds the type errors found in a program with type
+// annotations.
+type TypeError struct {
+	Diagnostics []types.Diagnostic
+}
+
+func (e *TypeError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// RuntimeError is an error object a program evaluated to.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Message
+}
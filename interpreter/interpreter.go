@@ -0,0 +1,299 @@
+// interpreter/interpreter.go
+
+// Package interpreter ties the lexer, parser, macro expander and evaluator
+// together into something that runs Monkey source code.
+package interpreter
+
+import (
+	"io"
+	"monkey/ast"
+	"monkey/coverage"
+	"monkey/evaluator"
+	"monkey/format"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/optimize"
+	"monkey/parser"
+	"monkey/profiler"
+	"monkey/resolver"
+	"monkey/token"
+	"monkey/types"
+	"sort"
+	"strings"
+)
+
+// Interpreter runs source code in a persistent environment, so that later
+// runs see the bindings and macros of earlier ones.
+type Interpreter struct {
+	Env      *object.Environment
+	MacroEnv *object.Environment
+
+	macroTrace   io.Writer
+	expander     *evaluator.Expander
+	wholeProgram bool
+
+	optimize     bool
+	optimizeDump io.Writer
+
+	file       string
+	searchPath []string
+	stdlibOnly bool
+	loader     *loader
+
+	coverage *coverage.Profile
+	profiler *profiler.Profiler
+}
+
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithMacroTrace makes the interpreter log every macro expansion step to w.
+func WithMacroTrace(w io.Writer) Option {
+	return func(in *Interpreter) {
+		in.macroTrace = w
+	}
+}
+
+// WithWholeProgram tells the interpreter that each source it runs is a
+// complete program, so that functions referring to names nothing binds are
+// reported before running rather than left for later inputs to define.
+func WithWholeProgram() Option {
+	return func(in *Interpreter) {
+		in.wholeProgram = true
+	}
+}
+
+// WithOptimizer makes the interpreter optimize each program before running
+// it. If dump is not nil, the optimized program is written to it in
+// canonical form.
+func WithOptimizer(dump io.Writer) Option {
+	return func(in *Interpreter) {
+		in.optimize = true
+		in.optimizeDump = dump
+	}
+}
+
+// WithFile tells the interpreter that the source it runs is read from the
+// file at path, which the paths of its imports are relative to. Without it
+// they are relative to the working directory.
+func WithFile(path string) Option {
+	return func(in *Interpreter) {
+		in.file = path
+	}
+}
+
+// WithSearchPath adds dirs to the directories searched, in order, for the
+// modules that are not found relative to the importing file.
+func WithSearchPath(dirs ...string) Option {
+	return func(in *Interpreter) {
+		in.searchPath = append(in.searchPath, dirs...)
+	}
+}
+
+// WithStdlibOnly makes the interpreter refuse to import modules other than
+// those of the standard library, so that the code it runs cannot read files.
+func WithStdlibOnly() Option {
+	return func(in *Interpreter) {
+		in.stdlibOnly = true
+	}
+}
+
+// WithCoverage makes the interpreter count in profile the statements and
+// branches that run of the programs it evaluates, and of the modules they
+// import, if they are read from files.
+func WithCoverage(profile *coverage.Profile) Option {
+	return func(in *Interpreter) {
+		in.coverage = profile
+	}
+}
+
+// WithProfiler makes the interpreter record in p the function calls of
+// the programs it evaluates, and of the modules they import. Starting and
+// stopping p is left to the caller.
+func WithProfiler(p *profiler.Profiler) Option {
+	return func(in *Interpreter) {
+		in.profiler = p
+	}
+}
+
+func New(opts ...Option) *Interpreter {
+	in := &Interpreter{}
+	for _, opt := range opts {
+		opt(in)
+	}
+	in.Reset()
+	return in
+}
+
+// Reset discards every binding and macro defined so far, and every module
+// imported, so that modules are loaded again when next imported.
+func (in *Interpreter) Reset() {
+	in.loader = newLoader(in)
+	in.newEnv()
+}
+
+// newEnv gives the interpreter fresh environments that import modules with
+// its loader.
+func (in *Interpreter) newEnv() {
+	in.Env = object.NewEnvironment()
+	in.MacroEnv = object.NewEnvironment()
+	in.Env.SetMacroEnv(in.MacroEnv)
+	in.Env.SetImporter(in.loader, in.file)
+	in.Env.SetObserver(newObserver(in.coverage, in.profiler))
+	in.MacroEnv.SetImporter(in.loader, in.file)
+	in.expander = &evaluator.Expander{Env: in.MacroEnv, Trace: in.macroTrace}
+}
+
+// Parse parses src, defines the macros it declares, expands its macro
+// calls and, if the interpreter has an optimizer, optimizes it. The modules
+// src imports are loaded, so that their macros can be expanded. The error
+// is a *ParseError or a *MacroError, or a *RuntimeError if loading a module
+// was interrupted.
+func (in *Interpreter) Parse(src string) (ast.Node, error) {
+	in.Env.ClearInterrupt()
+	in.MacroEnv.ClearInterrupt()
+	program, err := in.parse(src)
+	if err != nil {
+		return nil, err
+	}
+	node, err := in.expand(program)
+	if err == nil && in.MacroEnv.Interrupted() {
+		return nil, &RuntimeError{Err: &object.Error{Message: "interrupted"}}
+	}
+	return node, err
+}
+
+func (in *Interpreter) parse(src string) (*ast.Program, error) {
+	l := lexer.New(src)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Messages: p.Errors()}
+	}
+	return program, nil
+}
+
+func (in *Interpreter) expand(program *ast.Program) (ast.Node, error) {
+	evaluator.DefineMacros(program, in.MacroEnv)
+	expanded, diagnostics := in.expander.Expand(program)
+	if len(diagnostics) != 0 {
+		return nil, &MacroError{Diagnostics: diagnostics}
+	}
+	if program, ok := expanded.(*ast.Program); ok && in.optimize {
+		return in.optimizeProgram(program), nil
+	}
+	return expanded, nil
+}
+
+// optimizeProgram optimizes program. Unused lets are only removed from
+// whole programs, since later inputs of a session may use them.
+func (in *Interpreter) optimizeProgram(program *ast.Program) *ast.Program {
+	passes := optimize.Passes
+	if !in.wholeProgram {
+		passes = []optimize.Pass{optimize.FoldConstants, optimize.EliminateDeadBranches}
+	}
+	program = optimize.Optimize(program, passes...)
+	if in.optimizeDump != nil {
+		format.Node(in.optimizeDump, program)
+	}
+	return program
+}
+
+// Eval evaluates a node returned by Parse. Identifiers of a program that
+// would not be found are reported as a *ResolveError before it runs, and so
+// are the type errors of a program with type annotations, as a *TypeError;
+// an error object it evaluates to is returned as a *RuntimeError.
+func (in *Interpreter) Eval(node ast.Node) (object.Object, error) {
+	in.Env.ClearInterrupt()
+	return in.eval(node)
+}
+
+// eval evaluates node as Eval does, without clearing an interrupt: that of
+// a module is the interrupt of the code importing it.
+func (in *Interpreter) eval(node ast.Node) (object.Object, error) {
+	if program, ok := node.(*ast.Program); ok {
+		if err := in.resolve(program); err != nil {
+			return nil, err
+		}
+		if types.Annotated(program) {
+			if result := types.Check(program); len(result.Diagnostics) != 0 {
+				return nil, &TypeError{Diagnostics: result.Diagnostics}
+			}
+		}
+		if in.coverage != nil && in.file != "" {
+			in.coverage.Add(in.file, program)
+		}
+	}
+
+	evaluated := evaluator.Eval(node, in.Env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	return evaluated, nil
+}
+
+// resolve annotates the identifiers of program with what they refer to.
+func (in *Interpreter) resolve(program *ast.Program) error {
+	r := &resolver.Resolver{
+		Globals: func(name string) bool {
+			_, ok := in.Env.Get(name)
+			return ok
+		},
+		Builtins:    evaluator.IsBuiltin,
+		LateGlobals: !in.wholeProgram,
+	}
+	if table := r.Resolve(program); len(table.Diagnostics) != 0 {
+		return &ResolveError{Diagnostics: table.Diagnostics}
+	}
+	return nil
+}
+
+// Run parses and evaluates src. The result is nil if the program evaluates
+// to nothing, e.g. when it ends in a let statement.
+func (in *Interpreter) Run(src string) (object.Object, error) {
+	program, err := in.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return in.Eval(program)
+}
+
+// Call calls fn, a function value, with args. An error object it returns is
+// returned as a *RuntimeError.
+func (in *Interpreter) Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	in.Env.ClearInterrupt()
+	result := evaluator.Apply(fn, args...)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	return result, nil
+}
+
+// Interrupt stops the evaluation or macro expansion in progress, which then
+// fails with an "interrupted" error. It may be called from any goroutine.
+func (in *Interpreter) Interrupt() {
+	in.Env.Interrupt()
+	in.MacroEnv.Interrupt()
+}
+
+// Complete returns the keywords, builtins, bindings and macros that start
+// with prefix, sorted and without duplicates.
+func (in *Interpreter) Complete(prefix string) []string {
+	var names []string
+	names = append(names, token.Keywords()...)
+	names = append(names, evaluator.BuiltinNames()...)
+	names = append(names, in.Env.Names()...)
+	names = append(names, in.MacroEnv.Names()...)
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
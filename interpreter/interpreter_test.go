@@ -0,0 +1,170 @@
+// interpreter/interpreter_test.go
+
+package interpreter
+
+import (
+	"errors"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	in := New()
+
+	if _, err := in.Run(`let double = macro(x) { quote(unquote(x) * 2) }; let a = 21;`); err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+
+	result, err := in.Run(`double(a)`)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	integer, ok := result.(*object.Integer)
+	if !ok || integer.Value != 42 {
+		t.Errorf("wrong result. want=42, got=%v", result)
+	}
+
+	in.Reset()
+	if _, err := in.Run(`a`); err == nil {
+		t.Errorf("expected bindings to be discarded by Reset")
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	in := New()
+
+	_, err := in.Run(`let = 1;`)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("expected a *ParseError. got=%T (%v)", err, err)
+	}
+
+	_, err = in.Run(`let m = macro(a) { a }; m(1, 2);`)
+	var macroErr *MacroError
+	if !errors.As(err, &macroErr) {
+		t.Errorf("expected a *MacroError. got=%T (%v)", err, err)
+	}
+
+	_, err = in.Run(`1 + true`)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a *RuntimeError. got=%T (%v)", err, err)
+	}
+	if err.Error() != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	in := New()
+
+	// Nothing runs when an identifier would not be found.
+	_, err := in.Run("let a = 1;\nputs(a); b")
+	var resolveErr *ResolveError
+	if !errors.As(err, &resolveErr) {
+		t.Fatalf("expected a *ResolveError. got=%T (%v)", err, err)
+	}
+	if err.Error() != "2:10: identifier not found: b" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+	if _, err := in.Run("a"); err == nil {
+		t.Errorf("a program with an unresolved identifier was run")
+	}
+
+	// A session's functions may refer to globals a later input defines.
+	if _, err := in.Run("let f = fn() { g() };"); err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if _, err := in.Run("let g = fn() { 2 };"); err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	result, err := in.Run("f()")
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 2 {
+		t.Errorf("wrong result. want=2, got=%v", result)
+	}
+
+	// A whole program may not.
+	in = New(WithWholeProgram())
+	_, err = in.Run("let f = fn() { g() };")
+	if !errors.As(err, &resolveErr) {
+		t.Errorf("expected a *ResolveError. got=%T (%v)", err, err)
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	in := New()
+
+	// Nothing runs when an annotated program has type errors.
+	_, err := in.Run("let n: int = 1;\nputs(n); n + \"a\"")
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected a *TypeError. got=%T (%v)", err, err)
+	}
+	if err.Error() != "2:12: type mismatch: int + string" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+
+	// Annotations are ignored when evaluating.
+	result, err := in.Run("let add = fn(a: int, b: int) -> int { a + b }; add(1, 2)")
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 3 {
+		t.Errorf("wrong result. want=3, got=%v", result)
+	}
+
+	// Programs without annotations are not checked.
+	_, err = in.Run(`if (false) { 1 + "a" }; 1`)
+	if err != nil {
+		t.Errorf("an unannotated program was checked: %s", err)
+	}
+}
+
+func TestOptimizer(t *testing.T) {
+	var dump strings.Builder
+	in := New(WithWholeProgram(), WithOptimizer(&dump))
+
+	result, err := in.Run("let unused = 1; let f = fn(x) { x * (2 + 3) }; if (true) { f(2) }")
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 10 {
+		t.Errorf("wrong result. want=10, got=%v", result)
+	}
+
+	expected := "let f = fn(x) {\n    x * 5;\n};\nf(2);\n"
+	if dump.String() != expected {
+		t.Errorf("wrong dump.\nwant=%q\ngot= %q", expected, dump.String())
+	}
+
+	// A session keeps its unused globals for later inputs.
+	in = New(WithOptimizer(nil))
+	if _, err := in.Run("let unused = 1 + 1; 0"); err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if _, err := in.Run("let x = 0; unused"); err != nil {
+		t.Errorf("the unused global was removed: %s", err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	in := New()
+	if _, err := in.Run(`let length = 1; let lift = macro(x) { x };`); err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+
+	got := in.Complete("le")
+	expected := []string{"len", "length", "let"}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Complete(%q) wrong. want=%v, got=%v", "le", expected, got)
+	}
+
+	got = in.Complete("li")
+	if len(got) != 1 || got[0] != "lift" {
+		t.Errorf("Complete(%q) wrong. want=[lift], got=%v", "li", got)
+	}
+}
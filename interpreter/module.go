@@ -0,0 +1,241 @@
+// interpreter/module.go
+
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/coverage"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/profiler"
+	"monkey/stdlib"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loader loads the modules imported by the code an interpreter runs. Each
+// module is evaluated once, by an interpreter of its own, and the module
+// it exports is cached for every later import of the same file, as is the
+// error if it fails.
+type loader struct {
+	// root is the interpreter modules are imported into; interrupting it
+	// stops the code of its modules too.
+	root *Interpreter
+
+	searchPath []string
+	stdlibOnly bool
+	macroTrace io.Writer
+	optimize   bool
+	coverage   *coverage.Profile
+	profiler   *profiler.Profiler
+
+	modules map[string]*object.Module
+	failed  map[string]error
+
+	// loading holds the files being loaded, outermost first, to detect
+	// modules that import themselves.
+	loading []loading
+}
+
+type loading struct {
+	path string // as found, for messages
+	abs  string
+}
+
+func newLoader(in *Interpreter) *loader {
+	l := &loader{
+		root:       in,
+		searchPath: in.searchPath,
+		stdlibOnly: in.stdlibOnly,
+		macroTrace: in.macroTrace,
+		optimize:   in.optimize,
+		coverage:   in.coverage,
+		profiler:   in.profiler,
+		modules:    make(map[string]*object.Module),
+		failed:     make(map[string]error),
+	}
+	if in.file != "" {
+		if abs, err := filepath.Abs(in.file); err == nil {
+			l.loading = append(l.loading, loading{path: in.file, abs: abs})
+		}
+	}
+	return l
+}
+
+// Import returns the module at path, as imported by the file from. Paths
+// starting with std/ name modules of the standard library; others are
+// refused if the loader may only import those.
+func (l *loader) Import(path, from string) (*object.Module, error) {
+	if path == stdlib.Native {
+		return nativeModule(), nil
+	}
+	if strings.HasPrefix(path, stdlib.Prefix) {
+		src, ok := stdlib.Source(path)
+		if !ok {
+			return nil, errors.New("no such module in the standard library")
+		}
+		return l.cached(path, path, func() (*object.Module, error) {
+			return l.load(path, "", src)
+		})
+	}
+
+	if l.stdlibOnly {
+		return nil, errors.New("only modules of the standard library can be imported")
+	}
+	found, err := l.find(path, from)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(found)
+	if err != nil {
+		return nil, err
+	}
+	return l.cached(found, abs, func() (*object.Module, error) {
+		src, err := os.ReadFile(found)
+		if err != nil {
+			return nil, err
+		}
+		return l.load(found, found, string(src))
+	})
+}
+
+// nativeModule returns the module imported as stdlib.Native.
+func nativeModule() *object.Module {
+	exports := make(map[string]object.Object, len(evaluator.Natives))
+	for name, fn := range evaluator.Natives {
+		exports[name] = fn
+	}
+	return &object.Module{Path: stdlib.Native, Exports: exports}
+}
+
+// cached returns the module with the key abs if it has been loaded, and
+// loads it with load otherwise. found is the path to show in messages.
+// Loads that fail because they were interrupted are not cached.
+func (l *loader) cached(found, abs string, load func() (*object.Module, error)) (*object.Module, error) {
+	if module, ok := l.modules[abs]; ok {
+		return module, nil
+	}
+	if err, ok := l.failed[abs]; ok {
+		return nil, err
+	}
+	for i, file := range l.loading {
+		if file.abs == abs {
+			var cycle []string
+			for _, file := range l.loading[i:] {
+				cycle = append(cycle, file.path)
+			}
+			cycle = append(cycle, found)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	l.loading = append(l.loading, loading{path: found, abs: abs})
+	module, err := load()
+	l.loading = l.loading[:len(l.loading)-1]
+	if err != nil {
+		err = fmt.Errorf("%s: %w", found, err)
+		if !l.root.Env.Interrupted() {
+			l.failed[abs] = err
+		}
+		return nil, err
+	}
+	l.modules[abs] = module
+	return module, nil
+}
+
+// find returns the file path names, looking first relative to the
+// directory of the file from, or of the working directory if from is
+// empty, and then in each directory of the search path.
+func (l *loader) find(path, from string) (string, error) {
+	if filepath.IsAbs(path) {
+		if isFile(path) {
+			return path, nil
+		}
+		return "", errors.New("module not found")
+	}
+
+	dirs := []string{"."}
+	if from != "" {
+		dirs[0] = filepath.Dir(from)
+	}
+	dirs = append(dirs, l.searchPath...)
+
+	for _, dir := range dirs {
+		if candidate := filepath.Join(dir, path); isFile(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("module not found")
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// load evaluates src, the module imported as path from the file file, as
+// a whole program and returns what it exports: the values and macros bound
+// by its top-level export let statements.
+func (l *loader) load(path, file, src string) (*object.Module, error) {
+	in := &Interpreter{
+		macroTrace:   l.macroTrace,
+		wholeProgram: true,
+		optimize:     l.optimize,
+		file:         file,
+		loader:       l,
+		coverage:     l.coverage,
+		profiler:     l.profiler,
+	}
+	in.newEnv()
+	in.Env.ShareControl(l.root.Env)
+	in.MacroEnv.ShareControl(l.root.MacroEnv)
+
+	program, err := in.parse(src)
+	if err != nil {
+		return nil, err
+	}
+	// Collect the exports before macro definitions are removed.
+	exported := exportedNames(program)
+
+	node, err := in.expand(program)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := in.eval(node); err != nil {
+		return nil, err
+	}
+
+	module := &object.Module{
+		Path:    path,
+		Exports: make(map[string]object.Object),
+		Macros:  make(map[string]*object.Macro),
+	}
+	for _, name := range exported {
+		if macro, ok := in.MacroEnv.Get(name); ok {
+			if macro, ok := macro.(*object.Macro); ok {
+				module.Macros[name] = macro
+				continue
+			}
+		}
+		if val, ok := in.Env.Get(name); ok {
+			module.Exports[name] = val
+		}
+	}
+	return module, nil
+}
+
+// exportedNames returns the names bound by the top-level export let
+// statements of program.
+func exportedNames(program *ast.Program) []string {
+	var names []string
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let.Exported {
+			names = append(names, let.Name.Value)
+		}
+	}
+	return names
+}
@@ -0,0 +1,255 @@
+// interpreter/module_test.go
+
+package interpreter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"monkey/coverage"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/profiler"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFiles writes files, by name, to a new temporary directory and
+// returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib/math.mk": `
+let secret = 10;
+export let square = fn(x) { x * x };
+export let offset = secret + 1;
+export let twice = macro(x) { quote(unquote(x) + unquote(x)) };
+`,
+	})
+
+	in := New(WithFile(filepath.Join(dir, "main.mk")))
+	result, err := in.Run(`import "lib/math.mk" as math; math["square"](3) + math["offset"] + math["twice"](5)`)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	integer, ok := result.(*object.Integer)
+	if !ok || integer.Value != 30 {
+		t.Errorf("wrong result. want=30, got=%v", result)
+	}
+
+	_, err = in.Run(`math["secret"]`)
+	if err == nil || !strings.Contains(err.Error(), `has no export "secret"`) {
+		t.Errorf("expected unexported binding to be hidden. got=%v", err)
+	}
+}
+
+func TestImportEvaluatesOnce(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mk":      `import "shared.mk" as shared; export let shared = shared;`,
+		"b.mk":      `import "shared.mk" as shared; export let shared = shared;`,
+		"shared.mk": `export let value = 1;`,
+	})
+
+	in := New(WithFile(filepath.Join(dir, "main.mk")))
+	result, err := in.Run(`import "a.mk" as a; import "b.mk" as b; a["shared"] == b["shared"]`)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if result != evaluator.TRUE {
+		t.Errorf("expected both modules to share one import. got=%v", result)
+	}
+	if len(in.loader.modules) != 3 {
+		t.Errorf("wrong number of modules loaded. want=3, got=%d", len(in.loader.modules))
+	}
+}
+
+func TestImportSearchPath(t *testing.T) {
+	lib := writeFiles(t, map[string]string{"greet.mk": `export let hello = "hello";`})
+	dir := writeFiles(t, map[string]string{})
+
+	in := New(WithFile(filepath.Join(dir, "main.mk")), WithSearchPath(lib))
+	result, err := in.Run(`import "greet.mk" as greet; greet["hello"]`)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if str, ok := result.(*object.String); !ok || str.Value != "hello" {
+		t.Errorf("wrong result. want=hello, got=%v", result)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":   `import "cycle.mk" as cycle;`,
+		"cycle.mk":  `import "main.mk" as main;`,
+		"broken.mk": `let = 1;`,
+	})
+	main := filepath.Join(dir, "main.mk")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "missing.mk" as m;`, `import "missing.mk": module not found`},
+		{`import "broken.mk" as b;`, `import "broken.mk": ` + filepath.Join(dir, "broken.mk") + `: `},
+		{
+			`import "cycle.mk" as c;`,
+			"import cycle: " + main + " -> " + filepath.Join(dir, "cycle.mk") + " -> " + main,
+		},
+	}
+
+	for _, tt := range tests {
+		in := New(WithFile(main))
+		_, err := in.Run(tt.input)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("expected a *RuntimeError for %q. got=%T (%v)", tt.input, err, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %q. want to contain %q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestImportInterrupt(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"fib.mk":  "export let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };\n",
+		"slow.mk": "import \"fib.mk\" as f;\nexport let x = f[\"fib\"](40);\n",
+	})
+
+	tests := []string{
+		// A call into a module.
+		"import \"fib.mk\" as f;\nf[\"fib\"](40);",
+		// The top level of a module, while it is imported.
+		"import \"slow.mk\" as s;\ns[\"x\"];",
+	}
+
+	for _, input := range tests {
+		in := New(WithFile(filepath.Join(dir, "main.mk")))
+		done := make(chan error)
+		go func() {
+			_, err := in.Run(input)
+			done <- err
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		in.Interrupt()
+		select {
+		case err := <-done:
+			if err == nil || !strings.Contains(err.Error(), "interrupted") {
+				t.Errorf("wrong error for %q. got=%v", input, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("interrupt did not stop %q", input)
+		}
+	}
+}
+
+func TestImportStandardLibrary(t *testing.T) {
+	in := New()
+	result, err := in.Run(`import "std/list" as list; list["reduce"](list["map"]([1, 2, 3], fn(x) { x * x }), 0, fn(a, b) { a + b })`)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 14 {
+		t.Errorf("wrong result. want=14, got=%v", result)
+	}
+	if _, ok := in.loader.modules["std/list"]; !ok {
+		t.Errorf("expected std/list to be cached")
+	}
+	if _, ok := in.loader.modules["std/func"]; ok {
+		t.Errorf("expected std/func not to be loaded until imported")
+	}
+
+	_, err = in.Run(`import "std/missing" as missing;`)
+	if err == nil || !strings.Contains(err.Error(), `import "std/missing": no such module in the standard library`) {
+		t.Errorf("wrong error for a missing module. got=%v", err)
+	}
+}
+
+func TestImportStdlibOnly(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"lib.mk": `export let x = 1;`})
+
+	in := New(WithStdlibOnly(), WithFile(filepath.Join(dir, "main.mk")))
+	_, err := in.Run(`import "lib.mk" as lib;`)
+	if err == nil || !strings.Contains(err.Error(), `import "lib.mk": only modules of the standard library can be imported`) {
+		t.Errorf("wrong error for a file import. got=%v", err)
+	}
+	if _, err := in.Run(`import "std/list" as list;`); err != nil {
+		t.Errorf("standard library import failed: %s", err)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib.mk": "export let sign = fn(n) {\n    if (n < 0) { -1 } else { 1 }\n};\nexport let unused = fn() {\n    0\n};\n",
+	})
+
+	profile := coverage.New()
+	in := New(WithFile(filepath.Join(dir, "main.mk")), WithCoverage(profile))
+	if _, err := in.Run("import \"lib.mk\" as lib;\nlib[\"sign\"](5);\nimport \"std/list\" as list;"); err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+
+	var summary strings.Builder
+	profile.WriteSummary(&summary)
+	expected := filepath.Join(dir, "lib.mk") + ": 66.7% of statements (4/6), 50.0% of branches (1/2)\n" +
+		filepath.Join(dir, "main.mk") + ": 100.0% of statements (3/3), 100.0% of branches (0/0)\n"
+	if summary.String() != expected {
+		t.Errorf("wrong summary.\nwant=%s\ngot=%s", expected, summary.String())
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib.mk": "export let twice = fn(f, x) { f(f(x)) };\n",
+	})
+
+	p := profiler.New(0)
+	in := New(WithFile(filepath.Join(dir, "main.mk")), WithProfiler(p))
+	p.Start()
+	_, err := in.Run("import \"lib.mk\" as lib;\nlet inc = fn(x) { x + 1 };\nlib[\"twice\"](inc, 1);\nlib[\"twice\"](fn(x) { x * 2 }, 1);")
+	p.Stop()
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteProfile(&buf); err != nil {
+		t.Fatalf("WriteProfile failed: %s", err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data strings.Builder
+	if _, err := io.Copy(&data, zr); err != nil {
+		t.Fatal(err)
+	}
+
+	// Functions are named by their let, or by their position if they have
+	// none, and belong to the file they are defined in.
+	for _, s := range []string{"main", "twice", "inc", "fn@4:20", filepath.Join(dir, "lib.mk"), filepath.Join(dir, "main.mk")} {
+		if !strings.Contains(data.String(), s) {
+			t.Errorf("profile does not name %q", s)
+		}
+	}
+}
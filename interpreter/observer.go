@@ -0,0 +1,63 @@
+// interpreter/observer.go
+
+package interpreter
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/coverage"
+	"monkey/object"
+	"monkey/profiler"
+)
+
+// observer reports the code an interpreter evaluates to its coverage
+// profile and profiler, either of which may be nil.
+type observer struct {
+	coverage *coverage.Profile
+	profiler *profiler.Profiler
+}
+
+// newObserver returns the observer for profile and p, or nil if both are
+// nil, so that the evaluator has nothing to report to.
+func newObserver(profile *coverage.Profile, p *profiler.Profiler) object.Observer {
+	if profile == nil && p == nil {
+		return nil
+	}
+	return &observer{coverage: profile, profiler: p}
+}
+
+func (o *observer) Statement(stmt ast.Statement) {
+	if o.coverage != nil {
+		o.coverage.Statement(stmt)
+	}
+}
+
+func (o *observer) Branch(node *ast.IfExpression, taken bool) {
+	if o.coverage != nil {
+		o.coverage.Branch(node, taken)
+	}
+}
+
+func (o *observer) Enter(fn *object.Function, line int) {
+	if o.profiler != nil {
+		o.profiler.Enter(profiledFunction(fn), line)
+	}
+}
+
+func (o *observer) Exit() {
+	if o.profiler != nil {
+		o.profiler.Exit()
+	}
+}
+
+// profiledFunction identifies fn to the profiler. Functions that no let
+// binds are named after the position of their body.
+func profiledFunction(fn *object.Function) profiler.Function {
+	_, file, _ := fn.Env.Importer()
+	line, column := fn.Body.Token.Line, fn.Body.Token.Column
+	name := fn.Name
+	if name == "" {
+		name = fmt.Sprintf("fn@%d:%d", line, column)
+	}
+	return profiler.Function{Name: name, File: file, Line: line}
+}
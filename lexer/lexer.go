@@ -5,6 +5,7 @@ package lexer
 import (
 	"monkey/token"
 	"monkey/utils"
+	"strings"
 )
 
 type Lexer struct {
@@ -12,15 +13,24 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line         int
+	column       int
+	comments     []token.Token
+	unterminated bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -38,10 +48,26 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 
+	line, column := l.line, l.column
+	tok := l.nextToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
+
+// Offset returns the byte offset in the input just past the last token
+// returned by NextToken.
+func (l *Lexer) Offset() int {
+	if l.position > len(l.input) {
+		return len(l.input)
+	}
+	return l.position
+}
+
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -54,7 +80,13 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = token.Token{Type: token.PLUS, Literal: string(l.ch)}
 	case '-':
-		tok = token.Token{Type: token.MINUS, Literal: string(l.ch)}
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = token.Token{Type: token.MINUS, Literal: string(l.ch)}
+		}
 	case '/':
 		tok = token.Token{Type: token.SLASH, Literal: string(l.ch)}
 	case '*':
@@ -111,29 +143,80 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// escapes maps the character after a backslash in a string to the
+// character it stands for.
+var escapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'"':  '"',
+	'\\': '\\',
+}
+
 func (l *Lexer) readString() string {
-	position := l.position + 1
+	var out strings.Builder
 	for {
 		l.readChar()
+		if l.ch == '\\' {
+			l.readChar()
+			if ch, ok := escapes[l.ch]; ok {
+				out.WriteByte(ch)
+				continue
+			}
+			// Unknown escapes are kept as written.
+			out.WriteByte('\\')
+		}
 		if l.ch == '"' || l.ch == 0 {
+			l.unterminated = l.ch == 0
 			break
 		}
+		out.WriteByte(l.ch)
 	}
-	return l.input[position:l.position]
+	return out.String()
 }
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for utils.IsLetter(l.ch) {
+	for utils.IsLetter(l.ch) || utils.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			l.readComment()
+		default:
+			return
+		}
+	}
+}
+
+// readComment reads a comment running from "//" to the end of the line.
+func (l *Lexer) readComment() {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+	tok.Literal = l.input[position:l.position]
+	l.comments = append(l.comments, tok)
+}
+
+// Comments returns the comments skipped over so far. NextToken never
+// returns them, so they are invisible to the parser.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// Unterminated reports whether the last string literal read ran into the
+// end of the input before its closing quote.
+func (l *Lexer) Unterminated() bool {
+	return l.unterminated
 }
 
 func (l *Lexer) readNumber() string {
@@ -32,6 +32,10 @@ if (5 < 10) {
 [1,2];
 {"foo": "bar"}
 macro(x, y) { x + y; }
+null
+macroexpand_1
+fn(a: int) -> [string]
+x1 1x
 `
 	tests := []struct {
 		expectedType    token.TokenType
@@ -147,7 +151,22 @@ macro(x, y) { x + y; }
 		{token.IDENT, "y"},
 		{token.SEMICOLON, ";"},
 		{token.RBRACE, "}"},
-		
+		{token.NULL, "null"},
+		{token.IDENT, "macroexpand_1"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "string"},
+		{token.RBRACKET, "]"},
+		{token.IDENT, "x1"},
+		{token.INT, "1"},
+		{token.IDENT, "x"},
+
 		{token.EOF, ""},
 	}
 
@@ -164,3 +183,130 @@ macro(x, y) { x + y; }
 		}
 	}
 }
+
+func TestNextTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x + "a b";
+`
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"a b", 2, 7},
+		{";", 2, 12},
+		{"", 3, 1},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal value. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - wrong position for %q. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	input := `let s = "a b`
+	expected := []int{3, 5, 7, 12, 12}
+
+	l := New(input)
+	for i, offset := range expected {
+		l.NextToken()
+		if l.Offset() != offset {
+			t.Fatalf("tests[%d] - wrong offset. expected=%d, got=%d", i, offset, l.Offset())
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"tab\there"`, "tab\there"},
+		{`"\r"`, "\r"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\q"`, `\q`},
+		{`"open\`, `open\`},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != token.STRING {
+			t.Fatalf("%s - wrong token type. expected=%q, got=%q", tt.input, token.STRING, tok.Type)
+		}
+		if tok.Literal != tt.expected {
+			t.Errorf("%s - wrong literal value. expected=%q, got=%q", tt.input, tt.expected, tok.Literal)
+		}
+	}
+}
+
+func TestUnterminated(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"closed"`, false},
+		{`"open`, true},
+		{`"open\`, true},
+		{`"say \"hi`, true},
+		{`// say "hi`, false},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+		if l.Unterminated() != tt.expected {
+			t.Errorf("%s - wrong Unterminated. expected=%t, got=%t", tt.input, tt.expected, l.Unterminated())
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 10 / 2; // trailing
+"// not a comment"
+//`
+
+	expectedTokens := []string{"let", "x", "=", "10", "/", "2", ";", "// not a comment", ""}
+	l := New(input)
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+		if tok.Literal != expected {
+			t.Fatalf("tokens[%d] - wrong literal value. expected=%q, got=%q", i, expected, tok.Literal)
+		}
+	}
+
+	expectedComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// leading", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// trailing", Line: 2, Column: 17},
+		{Type: token.COMMENT, Literal: "//", Line: 4, Column: 1},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expectedComments), len(comments))
+	}
+	for i, expected := range expectedComments {
+		if comments[i] != expected {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expected, comments[i])
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// lint/check.go
+
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/resolver"
+	"monkey/token"
+	"strings"
+)
+
+// binding is a name bound by a let or import statement or a parameter.
+type binding struct {
+	name  string
+	ident *ast.Identifier
+	param bool
+	value ast.Expression // the bound value of a let
+	used  bool
+}
+
+// checker walks a program resolved by the resolver package, reporting
+// problems.
+type checker struct {
+	table       *resolver.Table
+	diagnostics []Diagnostic
+	bindings    []*binding
+	defs        map[*ast.Identifier]*binding
+
+	// quoted holds the names used in quoted code, which may refer to any
+	// binding of the name once a macro expands it.
+	quoted map[string]bool
+
+	// defining holds the let bindings whose values are being checked. A
+	// function referring to itself does not count as a use.
+	defining []*binding
+}
+
+func (c *checker) report(tok token.Token, rule, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{tok.Line, tok.Column, rule, fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) define(ident *ast.Identifier, param bool, value ast.Expression) *binding {
+	b := &binding{name: ident.Value, ident: ident, param: param, value: value}
+	c.defs[ident] = b
+	c.bindings = append(c.bindings, b)
+	return b
+}
+
+// lookup returns the binding ident refers to, or nil.
+func (c *checker) lookup(ident *ast.Identifier) *binding {
+	if ident.Symbol == nil || ident.Symbol.Def == nil {
+		return nil
+	}
+	return c.defs[ident.Symbol.Def]
+}
+
+// declare defines the let and import bindings in statements, leaving out
+// those inside function and macro literals. params are the parameters of
+// the function the statements are the body of, if any. Exported lets count
+// as used, since other files may use them, and so do global lets named
+// test_, which the test runner calls.
+func (c *checker) declare(statements []ast.Statement, params map[string]bool) {
+	for _, stmt := range statements {
+		ast.Walk(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.LetStatement:
+				if params[node.Name.Value] {
+					c.report(node.Name.Token, SHADOWED_PARAM, "let %s shadows the parameter %s", node.Name.Value, node.Name.Value)
+				}
+				b := c.define(node.Name, false, node.Value)
+				b.used = node.Exported || params == nil && strings.HasPrefix(node.Name.Value, "test_")
+			case *ast.ImportStatement:
+				c.define(node.Name, false, nil)
+			case *ast.FunctionLiteral, *ast.MacroLiteral:
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// function checks the parameters and body of a function or macro literal.
+func (c *checker) function(params []*ast.Identifier, body *ast.BlockStatement) {
+	names := make(map[string]bool)
+	for _, param := range params {
+		if names[param.Value] {
+			c.report(param.Token, SHADOWED_PARAM, "parameter %s is declared twice", param.Value)
+		} else if outer := c.defs[c.table.Shadowed[param]]; outer != nil && outer.param {
+			c.report(param.Token, SHADOWED_PARAM, "parameter %s shadows the parameter of an enclosing function at %d:%d",
+				param.Value, outer.ident.Token.Line, outer.ident.Token.Column)
+		}
+		names[param.Value] = true
+		c.define(param, true, nil)
+	}
+	c.declare(body.Statements, names)
+	c.statements(body.Statements, false)
+}
+
+// statements checks a list of statements, the value of the last of which
+// is used if used is set.
+func (c *checker) statements(statements []ast.Statement, used bool) {
+	terminated, reported := false, false
+	for i, stmt := range statements {
+		if terminated && !reported {
+			c.report(statementToken(stmt), UNREACHABLE, "unreachable code")
+			reported = true
+		}
+		c.statement(stmt, used && i == len(statements)-1)
+		terminated = terminated || terminates(stmt)
+	}
+}
+
+func (c *checker) statement(stmt ast.Statement, used bool) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.defining = append(c.defining, c.defs[stmt.Name])
+		c.expression(stmt.Value, true)
+		c.defining = c.defining[:len(c.defining)-1]
+	case *ast.ReturnStatement:
+		c.expression(stmt.ReturnValue, true)
+	case *ast.ExpressionStatement:
+		c.expression(stmt.Expression, used)
+	case *ast.BlockStatement:
+		c.statements(stmt.Statements, used)
+	}
+}
+
+// expression checks e, whose value is used if used is set.
+func (c *checker) expression(e ast.Expression, used bool) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		c.use(c.lookup(e))
+	case *ast.PrefixExpression:
+		c.expression(e.Right, true)
+	case *ast.InfixExpression:
+		c.expression(e.Left, true)
+		c.expression(e.Right, true)
+	case *ast.IfExpression:
+		if used && e.Alternative == nil {
+			c.report(e.Token, IF_VALUE, "the value of an if without an else is used")
+		}
+		c.expression(e.Condition, true)
+		c.statements(e.Consequence.Statements, used)
+		if e.Alternative != nil {
+			c.statements(e.Alternative.Statements, used)
+		}
+	case *ast.FunctionLiteral:
+		c.function(e.Parameters, e.Body)
+	case *ast.MacroLiteral:
+		c.function(e.Parameters, e.Body)
+	case *ast.CallExpression:
+		c.expression(e.Function, true)
+		if e.Function.TokenLiteral() == "quote" {
+			c.quote(e.Arguments)
+			return
+		}
+		for _, arg := range e.Arguments {
+			c.expression(arg, true)
+		}
+		c.arity(e)
+	case *ast.IndexExpression:
+		c.expression(e.Left, true)
+		c.expression(e.Index, true)
+	case *ast.ArrayLiteral:
+		for _, elem := range e.Elements {
+			c.expression(elem, true)
+		}
+	case *ast.HashLiteral:
+		for _, key := range e.Keys() {
+			c.expression(key, true)
+			c.expression(e.Pairs[key], true)
+		}
+	}
+}
+
+// quote checks the arguments of a quote call. Only the arguments of their
+// unquote calls are evaluated; the other names in them are noted in quoted.
+func (c *checker) quote(args []ast.Expression) {
+	for _, arg := range args {
+		ast.Walk(arg, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.CallExpression:
+				switch node.Function.TokenLiteral() {
+				case "unquote", "unquote_splice":
+					for _, arg := range node.Arguments {
+						c.expression(arg, true)
+					}
+					return false
+				}
+			case *ast.Identifier:
+				c.quoted[node.Value] = true
+			}
+			return true
+		})
+	}
+}
+
+// use marks b as used, unless it is being defined.
+func (c *checker) use(b *binding) {
+	if b == nil {
+		return
+	}
+	for _, d := range c.defining {
+		if d == b {
+			return
+		}
+	}
+	b.used = true
+}
+
+// arity reports a call to a function literal, directly or through a let
+// binding, with the wrong number of arguments.
+func (c *checker) arity(call *ast.CallExpression) {
+	fn, ok := call.Function.(*ast.FunctionLiteral)
+	name, tok := "function", call.Token
+	if ok {
+		tok = fn.Token
+	}
+	if ident, isIdent := call.Function.(*ast.Identifier); isIdent {
+		if b := c.lookup(ident); b != nil && !b.param {
+			fn, ok = b.value.(*ast.FunctionLiteral)
+		}
+		name, tok = ident.Value, ident.Token
+	}
+	if ok && len(fn.Parameters) != len(call.Arguments) {
+		c.report(tok, CALL_ARITY, "%s takes %d arguments, called with %d", name, len(fn.Parameters), len(call.Arguments))
+	}
+}
+
+// terminates reports whether stmt always returns.
+func terminates(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.BlockStatement:
+		for _, s := range stmt.Statements {
+			if terminates(s) {
+				return true
+			}
+		}
+	case *ast.ExpressionStatement:
+		if ie, ok := stmt.Expression.(*ast.IfExpression); ok && ie.Alternative != nil {
+			return terminates(ie.Consequence) && terminates(ie.Alternative)
+		}
+	}
+	return false
+}
+
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ImportStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	case *ast.BlockStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,151 @@
+// lint/lint.go
+
+// Package lint finds likely mistakes in Monkey programs without running
+// them: bindings that are never used, shadowed parameters, calls with the
+// wrong number of arguments, unreachable code and if expressions without
+// an else used as values.
+//
+// A file can turn rules off with a comment naming them:
+//
+//	// lint:disable unused-let, call-arity
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/resolver"
+	"monkey/token"
+	"sort"
+	"strings"
+)
+
+// rule IDs
+const (
+	UNUSED_LET     = "unused-let"
+	SHADOWED_PARAM = "shadowed-param"
+	CALL_ARITY     = "call-arity"
+	UNREACHABLE    = "unreachable"
+	IF_VALUE       = "if-value"
+
+	// SYNTAX and DIRECTIVE mark problems with the file itself; they cannot
+	// be disabled.
+	SYNTAX    = "syntax"
+	DIRECTIVE = "directive"
+)
+
+// Rule describes a check the linter makes.
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Rules lists the checks in the order they are documented.
+var Rules = []Rule{
+	{UNUSED_LET, "a let or import binding that is never referenced; names starting with _ or used in quoted code, exported lets and global test_ lets are exempt"},
+	{SHADOWED_PARAM, "a let or inner parameter that reuses the name of a parameter, or a repeated parameter"},
+	{CALL_ARITY, "a call to a known function literal with the wrong number of arguments"},
+	{UNREACHABLE, "a statement after a return, or after an if whose branches all return"},
+	{IF_VALUE, "an if without an else whose value is used, which is null when the condition is false"},
+}
+
+// Diagnostic is a problem found in a program.
+type Diagnostic struct {
+	Line, Column int
+	Rule         string
+	Message      string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+// Source lints the program src, leaving out the rules in disabled and
+// those turned off by lint:disable comments. A program that does not
+// parse gets its syntax errors instead.
+func Source(src []byte, disabled ...string) []Diagnostic {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		var diagnostics []Diagnostic
+		for _, d := range p.Diagnostics() {
+			diagnostics = append(diagnostics, Diagnostic{d.Line, d.Column, SYNTAX, d.Message})
+		}
+		return diagnostics
+	}
+
+	off, diagnostics := directives(l.Comments())
+	for _, id := range disabled {
+		off[id] = true
+	}
+	return sortDiagnostics(append(diagnostics, Program(program, off)...))
+}
+
+// Program lints program, leaving out the rules in disabled.
+func Program(program *ast.Program, disabled map[string]bool) []Diagnostic {
+	c := &checker{
+		table:  (&resolver.Resolver{}).Resolve(program),
+		defs:   make(map[*ast.Identifier]*binding),
+		quoted: make(map[string]bool),
+	}
+	c.declare(program.Statements, nil)
+	c.statements(program.Statements, false)
+
+	for _, b := range c.bindings {
+		if !b.param && !b.used && !c.quoted[b.name] && !strings.HasPrefix(b.name, "_") {
+			c.report(b.ident.Token, UNUSED_LET, "%s is declared but never used", b.name)
+		}
+	}
+
+	var diagnostics []Diagnostic
+	for _, d := range c.diagnostics {
+		if !disabled[d.Rule] {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return sortDiagnostics(diagnostics)
+}
+
+// directives returns the rules turned off by lint:disable comments, and
+// diagnostics for the names in them that are not rules.
+func directives(comments []token.Token) (map[string]bool, []Diagnostic) {
+	off := make(map[string]bool)
+	var diagnostics []Diagnostic
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Literal, "//"))
+		rest, ok := strings.CutPrefix(text, "lint:disable")
+		if !ok {
+			continue
+		}
+		for _, id := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if !isRule(id) {
+				diagnostics = append(diagnostics, Diagnostic{c.Line, c.Column, DIRECTIVE, fmt.Sprintf("unknown rule %q", id)})
+				continue
+			}
+			off[id] = true
+		}
+	}
+	return off, diagnostics
+}
+
+func isRule(id string) bool {
+	for _, r := range Rules {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func sortDiagnostics(diagnostics []Diagnostic) []Diagnostic {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diagnostics
+}
@@ -0,0 +1,123 @@
+// lint/lint_test.go
+
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; puts(x);", nil},
+		{"let x = 1; let _y = 2;", []string{"1:5: x is declared but never used (unused-let)"}},
+		{
+			"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } };",
+			[]string{"1:5: f is declared but never used (unused-let)"},
+		},
+		{"let a = fn() { b() }; let b = fn() { 1 }; a();", nil},
+		{"export let x = 1;", nil},
+		{
+			"let test_a = fn() { 1 }; let f = fn() { let test_b = 2; 3 }; f();",
+			[]string{"1:45: test_b is declared but never used (unused-let)"},
+		},
+		{
+			`import "a.mk" as a; import "b.mk" as b; b["f"]();`,
+			[]string{"1:18: a is declared but never used (unused-let)"},
+		},
+		{
+			"let x = 1; let x = 2; x;",
+			[]string{"1:5: x is declared but never used (unused-let)"},
+		},
+		{
+			"let f = fn(x) { let x = 2; x }; f(1);",
+			[]string{"1:21: let x shadows the parameter x (shadowed-param)"},
+		},
+		{
+			"let f = fn(x, x) { x }; f(1, 2);",
+			[]string{"1:15: parameter x is declared twice (shadowed-param)"},
+		},
+		{
+			"let f = fn(x) { fn(x) { x } }; f(1);",
+			[]string{"1:20: parameter x shadows the parameter of an enclosing function at 1:12 (shadowed-param)"},
+		},
+		{"let x = 1; let f = fn(x) { x }; f(x);", nil},
+		{
+			"let helper = fn() { 1 }; let x = 1; let y = 2; let m = macro() { quote(helper() + unquote(x)) }; m();",
+			[]string{"1:41: y is declared but never used (unused-let)"},
+		},
+		{
+			"let add = fn(a, b) { a + b }; add(1); add(1, 2); add(1, 2, 3); fn(a) { a }();",
+			[]string{
+				"1:31: add takes 2 arguments, called with 1 (call-arity)",
+				"1:50: add takes 2 arguments, called with 3 (call-arity)",
+				"1:64: function takes 1 arguments, called with 0 (call-arity)",
+			},
+		},
+		{"let g = fn(f) { f(1, 2) }; g(len);", nil},
+		{
+			"let f = fn() { return 1; puts(2); puts(3); }; f();",
+			[]string{"1:26: unreachable code (unreachable)"},
+		},
+		{
+			"let f = fn(x) { if (x) { return 1 } else { return 2 }; 3 }; f(true);",
+			[]string{"1:56: unreachable code (unreachable)"},
+		},
+		{"let f = fn(x) { if (x) { return 1 }; 2 }; f(true);", nil},
+		{"return 1;\nputs(2);", []string{"2:1: unreachable code (unreachable)"}},
+		{
+			"let a = if (true) { 1 }; puts(if (a) { 2 } + 1); if (a) { puts(a) }",
+			[]string{
+				"1:9: the value of an if without an else is used (if-value)",
+				"1:31: the value of an if without an else is used (if-value)",
+			},
+		},
+		{
+			"let a = if (true) { if (false) { 1 } } else { 2 }; a;",
+			[]string{"1:21: the value of an if without an else is used (if-value)"},
+		},
+		{"let a = 1;\nlet b = fn() { puts(1) }(;", []string{
+			"2:26: no prefix parse function for ; found (syntax)",
+			"2:27: expected next token to be ), got EOF instead (syntax)",
+		}},
+	}
+
+	for _, tt := range tests {
+		want := strings.Join(tt.expected, "\n")
+		if got := join(Source([]byte(tt.input))); got != want {
+			t.Errorf("wrong diagnostics for %q.\nwant=%s\ngot= %s", tt.input, want, got)
+		}
+	}
+}
+
+func TestDisable(t *testing.T) {
+	input := `// lint:disable unused-let, call-arity
+// lint:disable unknown
+let f = fn(a) { a };
+f(1, 2);
+return 1;
+f(1);
+`
+	expected := []string{
+		`2:1: unknown rule "unknown" (directive)`,
+		"6:1: unreachable code (unreachable)",
+	}
+	if got := join(Source([]byte(input))); got != strings.Join(expected, "\n") {
+		t.Errorf("wrong diagnostics.\nwant=%s\ngot= %s", strings.Join(expected, "\n"), got)
+	}
+
+	if got := join(Source([]byte(input), UNREACHABLE)); got != expected[0] {
+		t.Errorf("wrong diagnostics with unreachable disabled.\nwant=%s\ngot= %s", expected[0], got)
+	}
+}
+
+func join(diagnostics []Diagnostic) string {
+	lines := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,258 @@
+// lsp/analysis.go
+
+package lsp
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/resolver"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// macroTimeout bounds how long expanding the macros of a document may take,
+// since macro bodies are arbitrary code.
+const macroTimeout = time.Second
+
+// document is an open file and what the server worked out about it.
+type document struct {
+	uri   string
+	text  string
+	lines []string
+
+	program     *ast.Program
+	diagnostics []Diagnostic
+
+	symbols     []*symbol                   // in source order
+	identifiers []*ast.Identifier           // every identifier in the document
+	resolved    map[*ast.Identifier]*symbol // what each identifier refers to
+}
+
+// symbol is a name bound by a let statement or a parameter.
+type symbol struct {
+	name      string
+	kind      string // "let", "import" or "parameter"
+	def       *ast.Identifier
+	value     ast.Expression // the bound value of a let
+	container *symbol        // the let binding the function it is in, if any
+	refs      []*ast.Identifier
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{
+		uri:      uri,
+		text:     text,
+		lines:    strings.Split(text, "\n"),
+		resolved: make(map[*ast.Identifier]*symbol),
+	}
+
+	p := parser.New(lexer.New(text))
+	d.program = p.ParseProgram()
+	for _, diag := range p.Diagnostics() {
+		d.addDiagnostic(diag.Line, diag.Column, "parser", diag.Message)
+	}
+	if len(p.Diagnostics()) == 0 {
+		for _, diag := range expandMacros(d.program) {
+			d.addDiagnostic(diag.Line, diag.Column, "macro", "macro "+diag.Macro+": "+diag.Message)
+		}
+	}
+
+	(&resolver.Resolver{Forward: true}).Resolve(d.program)
+	c := &collector{doc: d, symbols: make(map[*ast.Identifier]*symbol)}
+	c.collect(d.program, nil)
+	c.link()
+	return d
+}
+
+// expandMacros expands the macros of a copy of program and returns the
+// problems found. Macros that panic are among them, rather than stopping
+// the server.
+func expandMacros(program *ast.Program) []evaluator.Diagnostic {
+	env := object.NewEnvironment()
+	timer := time.AfterFunc(macroTimeout, env.Interrupt)
+	defer timer.Stop()
+
+	copied := ast.Copy(program).(*ast.Program)
+	evaluator.DefineMacros(copied, env)
+	expander := &evaluator.Expander{Env: env, Recover: true}
+	_, diagnostics := expander.Expand(copied)
+	return diagnostics
+}
+
+func (d *document) addDiagnostic(line, column int, source, message string) {
+	start := d.position(line, column)
+	end := start
+	end.Character++
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Range:    Range{Start: start, End: end},
+		Severity: SeverityError,
+		Source:   source,
+		Message:  message,
+	})
+}
+
+// position converts a 1-based line and byte column, as kept in tokens, to
+// an LSP position.
+func (d *document) position(line, column int) Position {
+	if line < 1 || line > len(d.lines) {
+		return Position{Line: max(line-1, 0)}
+	}
+	text := d.lines[line-1]
+	column = min(max(column-1, 0), len(text))
+	character := 0
+	for _, r := range text[:column] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line - 1, Character: character}
+}
+
+// column converts an LSP position to a 1-based line and byte column.
+func (d *document) column(pos Position) (line, column int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, 1
+	}
+	text := d.lines[pos.Line]
+	character := 0
+	for i, r := range text {
+		if character >= pos.Character {
+			return pos.Line + 1, i + 1
+		}
+		character += utf16Len(r)
+	}
+	return pos.Line + 1, len(text) + 1
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 && utf8.ValidRune(r) {
+		return 2
+	}
+	return 1
+}
+
+// identRange returns the range ident covers.
+func (d *document) identRange(ident *ast.Identifier) Range {
+	return Range{
+		Start: d.position(ident.Token.Line, ident.Token.Column),
+		End:   d.position(ident.Token.Line, ident.Token.Column+len(ident.Value)),
+	}
+}
+
+func (d *document) location(ident *ast.Identifier) Location {
+	return Location{URI: d.uri, Range: d.identRange(ident)}
+}
+
+// identifierAt returns the identifier at pos, or nil.
+func (d *document) identifierAt(pos Position) *ast.Identifier {
+	line, column := d.column(pos)
+	for _, ident := range d.identifiers {
+		start := ident.Token.Column
+		if ident.Token.Line == line && start <= column && column <= start+len(ident.Value) {
+			return ident
+		}
+	}
+	return nil
+}
+
+// wordBefore returns the identifier characters just before pos.
+func (d *document) wordBefore(pos Position) string {
+	line, column := d.column(pos)
+	if line < 1 || line > len(d.lines) {
+		return ""
+	}
+	text := d.lines[line-1][:column-1]
+	start := len(text)
+	for start > 0 && isIdentByte(text[start-1]) {
+		start--
+	}
+	return text[start:]
+}
+
+func isIdentByte(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+// collector gathers the identifiers and symbols of a document whose
+// identifiers the resolver package has resolved.
+type collector struct {
+	doc     *document
+	symbols map[*ast.Identifier]*symbol // by definition
+}
+
+func (c *collector) define(ident *ast.Identifier, kind string, value ast.Expression, container *symbol) *symbol {
+	sym := &symbol{name: ident.Value, kind: kind, def: ident, value: value, container: container}
+	c.symbols[ident] = sym
+	c.doc.symbols = append(c.doc.symbols, sym)
+	return sym
+}
+
+// collect defines the symbols bound in node and records its identifiers,
+// in source order. container is the symbol of the let binding the function
+// node is in, if any.
+func (c *collector) collect(node ast.Node, container *symbol) {
+	ast.Walk(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if node.Name == nil {
+				return false
+			}
+			sym := c.define(node.Name, "let", node.Value, container)
+			c.doc.identifiers = append(c.doc.identifiers, node.Name)
+			if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+				c.function(fn.Parameters, fn.Body, sym)
+			} else if node.Value != nil {
+				c.collect(node.Value, container)
+			}
+			return false
+		case *ast.ImportStatement:
+			if node.Name != nil {
+				c.define(node.Name, "import", node.Path, container)
+				c.doc.identifiers = append(c.doc.identifiers, node.Name)
+			}
+			return false
+		case *ast.FunctionLiteral:
+			c.function(node.Parameters, node.Body, container)
+			return false
+		case *ast.MacroLiteral:
+			c.function(node.Parameters, node.Body, container)
+			return false
+		case *ast.Identifier:
+			if node != nil {
+				c.doc.identifiers = append(c.doc.identifiers, node)
+			}
+		}
+		return true
+	})
+}
+
+func (c *collector) function(params []*ast.Identifier, body *ast.BlockStatement, container *symbol) {
+	for _, param := range params {
+		if param != nil {
+			c.define(param, "parameter", nil, container)
+			c.doc.identifiers = append(c.doc.identifiers, param)
+		}
+	}
+	if body != nil {
+		c.collect(body, container)
+	}
+}
+
+// link links every identifier to the symbol it refers to, if any.
+func (c *collector) link() {
+	for _, ident := range c.doc.identifiers {
+		if ident.Symbol == nil || ident.Symbol.Def == nil {
+			continue
+		}
+		sym, ok := c.symbols[ident.Symbol.Def]
+		if !ok {
+			continue
+		}
+		c.doc.resolved[ident] = sym
+		if ident != sym.def {
+			sym.refs = append(sym.refs, ident)
+		}
+	}
+}
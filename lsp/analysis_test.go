@@ -0,0 +1,119 @@
+// lsp/analysis_test.go
+
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	text := `let x = 1;
+let add = fn(a, b) { let x = a + b; x };
+let twice = fn(f) { fn(v) { f(f(v)) } };
+add(x, later());
+let later = fn() { x };`
+
+	doc := newDocument("file:///test.mk", text)
+
+	tests := []struct {
+		line, column int // of a use
+		defLine      int // of the definition it resolves to, 0 if none
+		defColumn    int
+		kind         string
+	}{
+		{2, 37, 2, 26, "let"},       // x inside add is its local x
+		{2, 30, 2, 14, "parameter"}, // a
+		{3, 29, 3, 16, "parameter"}, // f inside the inner function
+		{3, 33, 3, 24, "parameter"}, // v
+		{4, 1, 2, 5, "let"},         // add
+		{4, 5, 1, 5, "let"},         // top-level x
+		{4, 8, 5, 5, "let"},         // later, defined after its use
+		{5, 20, 1, 5, "let"},        // x inside later
+		{4, 0, 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		var found *symbol
+		for _, ident := range doc.identifiers {
+			if ident.Token.Line == tt.line && ident.Token.Column == tt.column {
+				found = doc.resolved[ident]
+			}
+		}
+		if tt.defLine == 0 {
+			if found != nil {
+				t.Errorf("%d:%d resolved to %s at %d:%d, want nothing", tt.line, tt.column,
+					found.name, found.def.Token.Line, found.def.Token.Column)
+			}
+			continue
+		}
+		if found == nil {
+			t.Errorf("%d:%d did not resolve", tt.line, tt.column)
+			continue
+		}
+		if found.def.Token.Line != tt.defLine || found.def.Token.Column != tt.defColumn || found.kind != tt.kind {
+			t.Errorf("%d:%d resolved to %s %s at %d:%d, want %s at %d:%d", tt.line, tt.column,
+				found.kind, found.name, found.def.Token.Line, found.def.Token.Column, tt.kind, tt.defLine, tt.defColumn)
+		}
+	}
+}
+
+func TestDocumentDiagnostics(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{`let x = 1;`, nil},
+		{"let x 1;\nlet y = 2;", []string{"0:6 parser: expected next token to be =, got INT instead"}},
+		{
+			"let m = macro(a) { quote(a) };\nm(1, 2);",
+			[]string{"1:0 macro: macro m: wrong number of arguments. got=2, want=1"},
+		},
+		{
+			"let m = macro() {\n  let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) + f(n - 1) } };\n  f(40)\n};\nm();",
+			[]string{"4:0 macro: macro m: interrupted"},
+		},
+		{
+			"let m = macro() { quote() };\nm();",
+			[]string{"1:0 macro: macro m: wrong number of arguments. got=0, want=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		doc := newDocument("file:///test.mk", tt.text)
+		var got []string
+		for _, d := range doc.diagnostics {
+			got = append(got, fmt.Sprintf("%d:%d %s: %s", d.Range.Start.Line, d.Range.Start.Character, d.Source, d.Message))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong diagnostics for %q.\nwant=%q\ngot=%q", tt.text, tt.expected, got)
+		}
+	}
+}
+
+func TestPositionConversion(t *testing.T) {
+	doc := newDocument("file:///test.mk", "let s = \"héllo😀\"; s\nx")
+
+	tests := []struct {
+		line, column int
+		position     Position
+	}{
+		{1, 1, Position{0, 0}},
+		{1, 10, Position{0, 9}},
+		{1, 13, Position{0, 11}}, // after the two-byte é
+		{1, 16, Position{0, 14}}, // the emoji starts here
+		{1, 20, Position{0, 16}}, // after the four-byte emoji, two UTF-16 units
+		{2, 1, Position{1, 0}},
+	}
+
+	for _, tt := range tests {
+		if got := doc.position(tt.line, tt.column); got != tt.position {
+			t.Errorf("position(%d, %d) wrong. want=%v, got=%v", tt.line, tt.column, tt.position, got)
+		}
+		line, column := doc.column(tt.position)
+		if line != tt.line || column != tt.column {
+			t.Errorf("column(%v) wrong. want=%d:%d, got=%d:%d", tt.position, tt.line, tt.column, line, column)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+// lsp/server.go
+
+// Package lsp implements a Language Server Protocol server for Monkey. It
+// reports syntax and macro expansion errors, finds the definition and
+// references of let bindings and parameters, shows builtin signatures on
+// hover, and lists document symbols and completions.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/rpc"
+	"monkey/token"
+	"monkey/utils"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Server answers LSP requests read from one connection.
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document
+
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: make(map[string]*document)}
+}
+
+// Serve reads and answers messages until the client sends exit or closes
+// the connection.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, rpcErr := s.handle(msg)
+		if len(msg.ID) == 0 {
+			continue
+		}
+		response := &message{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			response.Result, err = json.Marshal(result)
+			if err != nil {
+				return err
+			}
+		}
+		if err := s.write(response); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads one message framed by a Content-Length header.
+func (s *Server) read() (*message, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (s *Server) write(msg *message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (s *Server) handle(msg *message) (interface{}, *rpc.Error) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &rpc.Error{Code: rpc.CodeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize()
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			return nil, s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/definition":
+		return s.definition(msg.Params)
+	case "textDocument/references":
+		return s.references(msg.Params)
+	case "textDocument/hover":
+		return s.hover(msg.Params)
+	case "textDocument/documentSymbol":
+		return s.documentSymbol(msg.Params)
+	case "textDocument/completion":
+		return s.completion(msg.Params)
+	}
+
+	if strings.HasPrefix(msg.Method, "$/") || len(msg.ID) == 0 {
+		// Notifications the server does not handle are ignored.
+		return nil, nil
+	}
+	return nil, &rpc.Error{Code: rpc.CodeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func decode(params json.RawMessage, v interface{}) *rpc.Error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpc.Error{Code: rpc.CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize() (interface{}, *rpc.Error) {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":       1, // full
+			"definitionProvider":     true,
+			"referencesProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"completionProvider":     map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{"name": "monkey"},
+	}, nil
+}
+
+// update analyzes the new text of a document and publishes its
+// diagnostics.
+func (s *Server) update(uri, text string) *rpc.Error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+
+	diagnostics := doc.diagnostics
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	err := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return &rpc.Error{Code: rpc.CodeInternalError, Message: err.Error()}
+	}
+	return nil
+}
+
+// symbolAt returns the document named in params and the symbol that the
+// identifier at its position refers to, if any.
+func (s *Server) symbolAt(params TextDocumentPositionParams) (*document, *symbol) {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	ident := doc.identifierAt(params.Position)
+	if ident == nil {
+		return doc, nil
+	}
+	return doc, doc.resolved[ident]
+}
+
+func (s *Server) definition(raw json.RawMessage) (interface{}, *rpc.Error) {
+	var params TextDocumentPositionParams
+	if err := decode(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, sym := s.symbolAt(params)
+	if sym == nil {
+		return nil, nil
+	}
+	return doc.location(sym.def), nil
+}
+
+func (s *Server) references(raw json.RawMessage) (interface{}, *rpc.Error) {
+	var params ReferenceParams
+	if err := decode(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, sym := s.symbolAt(params.TextDocumentPositionParams)
+	if sym == nil {
+		return []Location{}, nil
+	}
+
+	locations := []Location{}
+	if params.Context.IncludeDeclaration {
+		locations = append(locations, doc.location(sym.def))
+	}
+	for _, ref := range sym.refs {
+		locations = append(locations, doc.location(ref))
+	}
+	return locations, nil
+}
+
+func (s *Server) hover(raw json.RawMessage) (interface{}, *rpc.Error) {
+	var params TextDocumentPositionParams
+	if err := decode(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	ident := doc.identifierAt(params.Position)
+	if ident == nil {
+		return nil, nil
+	}
+
+	var text string
+	if sym, ok := doc.resolved[ident]; ok {
+		text = "```monkey\n" + describe(sym) + "\n```"
+	} else if signature, description, ok := evaluator.BuiltinSignature(ident.Value); ok {
+		text = "```monkey\n" + signature + "\n```\n" + description
+	} else {
+		return nil, nil
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    doc.identRange(ident),
+	}, nil
+}
+
+// describe returns a one-line summary of how sym is bound.
+func describe(sym *symbol) string {
+	if sym.kind == "parameter" {
+		return "parameter " + sym.name
+	}
+	if path, ok := sym.value.(*ast.StringLiteral); ok && sym.kind == "import" {
+		return fmt.Sprintf("import %s as %s", utils.Quote(path.Value), sym.name)
+	}
+	if fn, ok := sym.value.(*ast.FunctionLiteral); ok {
+		params := make([]string, len(fn.Parameters))
+		for i, p := range fn.Parameters {
+			params[i] = p.Value
+		}
+		return fmt.Sprintf("let %s = fn(%s)", sym.name, strings.Join(params, ", "))
+	}
+	if _, ok := sym.value.(*ast.MacroLiteral); ok {
+		return "let " + sym.name + " = macro"
+	}
+	return "let " + sym.name
+}
+
+func (s *Server) documentSymbol(raw json.RawMessage) (interface{}, *rpc.Error) {
+	var params DocumentSymbolParams
+	if err := decode(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return []SymbolInformation{}, nil
+	}
+
+	symbols := []SymbolInformation{}
+	for _, sym := range doc.symbols {
+		if sym.kind == "parameter" {
+			continue
+		}
+		info := SymbolInformation{Name: sym.name, Kind: SymbolVariable, Location: doc.location(sym.def)}
+		if _, ok := sym.value.(*ast.FunctionLiteral); ok {
+			info.Kind = SymbolFunction
+		} else if sym.kind == "import" {
+			info.Kind = SymbolModule
+		}
+		if sym.container != nil {
+			info.ContainerName = sym.container.name
+		}
+		symbols = append(symbols, info)
+	}
+	return symbols, nil
+}
+
+func (s *Server) completion(raw json.RawMessage) (interface{}, *rpc.Error) {
+	var params TextDocumentPositionParams
+	if err := decode(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return []CompletionItem{}, nil
+	}
+	prefix := doc.wordBefore(params.Position)
+
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+	add := func(item CompletionItem) {
+		if strings.HasPrefix(item.Label, prefix) && !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+	for _, sym := range doc.symbols {
+		add(CompletionItem{Label: sym.name, Kind: CompletionVariable, Detail: describe(sym)})
+	}
+	for _, name := range evaluator.BuiltinNames() {
+		signature, _, _ := evaluator.BuiltinSignature(name)
+		add(CompletionItem{Label: name, Kind: CompletionFunction, Detail: signature})
+	}
+	for _, keyword := range token.Keywords() {
+		add(CompletionItem{Label: keyword, Kind: CompletionKeyword})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items, nil
+}
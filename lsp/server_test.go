@@ -0,0 +1,123 @@
+// lsp/server_test.go
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///test.mk"
+
+const testText = `let add = fn(a, b) { a + b };
+let total = add(1, 2);
+len(total)
+`
+
+// frame encodes requests as LSP messages.
+func frame(requests ...string) string {
+	var out strings.Builder
+	for _, r := range requests {
+		fmt.Fprintf(&out, "Content-Length: %d\r\n\r\n%s", len(r), r)
+	}
+	return out.String()
+}
+
+// request returns a JSON-RPC request, or a notification if id is 0.
+func request(id int, method, params string) string {
+	if id == 0 {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","method":%q,"params":%s}`, method, params)
+	}
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, id, method, params)
+}
+
+func positionParams(line, character int) string {
+	return fmt.Sprintf(`{"textDocument":{"uri":%q},"position":{"line":%d,"character":%d}}`, testURI, line, character)
+}
+
+// readMessages decodes the messages the server wrote.
+func readMessages(t *testing.T, data []byte) []message {
+	t.Helper()
+	var messages []message
+	r := &Server{in: bufio.NewReader(bytes.NewReader(data))}
+	for {
+		msg, err := r.read()
+		if err != nil {
+			return messages
+		}
+		messages = append(messages, *msg)
+	}
+}
+
+func TestServer(t *testing.T) {
+	text, _ := json.Marshal(testText)
+	input := frame(
+		request(1, "initialize", `{}`),
+		request(0, "initialized", `{}`),
+		request(0, "textDocument/didOpen", fmt.Sprintf(`{"textDocument":{"uri":%q,"text":%s}}`, testURI, text)),
+		request(2, "textDocument/definition", positionParams(1, 13)),
+		request(3, "textDocument/references", fmt.Sprintf(
+			`{"textDocument":{"uri":%q},"position":{"line":0,"character":13},"context":{"includeDeclaration":true}}`, testURI)),
+		request(4, "textDocument/hover", positionParams(2, 1)),
+		request(5, "textDocument/hover", positionParams(0, 5)),
+		request(6, "textDocument/documentSymbol", fmt.Sprintf(`{"textDocument":{"uri":%q}}`, testURI)),
+		request(7, "textDocument/completion", positionParams(2, 2)),
+		request(0, "textDocument/didChange", fmt.Sprintf(
+			`{"textDocument":{"uri":%q},"contentChanges":[{"text":"let x 1;"}]}`, testURI)),
+		request(8, "textDocument/unknown", `{}`),
+		request(9, "shutdown", `null`),
+		request(0, "exit", `null`),
+	)
+
+	var out bytes.Buffer
+	if err := NewServer(strings.NewReader(input), &out).Serve(); err != nil {
+		t.Fatalf("Serve returned %v", err)
+	}
+
+	expected := []string{
+		`1 {"capabilities":{"completionProvider":{},"definitionProvider":true,"documentSymbolProvider":true,` +
+			`"hoverProvider":true,"referencesProvider":true,"textDocumentSync":1},"serverInfo":{"name":"monkey"}}`,
+		`textDocument/publishDiagnostics {"uri":"file:///test.mk","diagnostics":[]}`,
+		`2 {"uri":"file:///test.mk","range":{"start":{"line":0,"character":4},"end":{"line":0,"character":7}}}`,
+		`3 [{"uri":"file:///test.mk","range":{"start":{"line":0,"character":13},"end":{"line":0,"character":14}}},` +
+			`{"uri":"file:///test.mk","range":{"start":{"line":0,"character":21},"end":{"line":0,"character":22}}}]`,
+		`4 {"contents":{"kind":"markdown","value":"` + "```monkey\\nlen(value)\\n```\\n" +
+			`Returns the number of bytes in a string or elements in an array."},` +
+			`"range":{"start":{"line":2,"character":0},"end":{"line":2,"character":3}}}`,
+		`5 {"contents":{"kind":"markdown","value":"` + "```monkey\\nlet add = fn(a, b)\\n```" + `"},` +
+			`"range":{"start":{"line":0,"character":4},"end":{"line":0,"character":7}}}`,
+		`6 [{"name":"add","kind":12,"location":{"uri":"file:///test.mk","range":{"start":{"line":0,"character":4},"end":{"line":0,"character":7}}}},` +
+			`{"name":"total","kind":13,"location":{"uri":"file:///test.mk","range":{"start":{"line":1,"character":4},"end":{"line":1,"character":9}}}}]`,
+		`7 [{"label":"len","kind":3,"detail":"len(value)"},{"label":"let","kind":14}]`,
+		`textDocument/publishDiagnostics {"uri":"file:///test.mk","diagnostics":[{"range":{"start":{"line":0,"character":6},` +
+			`"end":{"line":0,"character":7}},"severity":1,"source":"parser","message":"expected next token to be =, got INT instead"}]}`,
+		`8 error -32601 method not found: textDocument/unknown`,
+		`9 null`,
+	}
+
+	messages := readMessages(t, out.Bytes())
+	var got []string
+	for _, msg := range messages {
+		switch {
+		case msg.Method != "":
+			got = append(got, msg.Method+" "+string(msg.Params))
+		case msg.Error != nil:
+			got = append(got, fmt.Sprintf("%s error %d %s", msg.ID, msg.Error.Code, msg.Error.Message))
+		default:
+			got = append(got, string(msg.ID)+" "+string(msg.Result))
+		}
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("wrong number of messages. want=%d, got=%d:\n%s", len(expected), len(got), strings.Join(got, "\n"))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("messages[%d] wrong.\nwant=%s\ngot= %s", i, expected[i], got[i])
+		}
+	}
+}
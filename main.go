@@ -4,32 +4,89 @@ package main
 
 import (
 	"fmt"
-	"monkey/repl"
 	"os"
-	"os/signal"
-	"os/user"
 )
 
-func main() {
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
-	}
+const usage = `Monkey is an interpreter for the Monkey programming language.
+
+Usage:
+
+	monkey <command> [arguments]
 
-	fmt.Printf("Hello %s! Welcome to the Monkey programming language REPL.\n", user.Username)
-	fmt.Printf("Feel free to type in commands\n")
+The commands are:
 
-	sigChan := make(chan os.Signal, 1)
-	done := make(chan bool, 1)
-	signal.Notify(sigChan, os.Interrupt)
+	run <file> [args...]   run a script; its arguments are bound to ARGS
+	eval -e '<expr>'       evaluate an expression and print the result
+	repl                   start an interactive session
+	rpc [-listen addr]     serve JSON-RPC 2.0 requests on stdio or a socket
+	lsp                    run a Language Server Protocol server on stdio
+	fmt [-w] [files...]    print files, or standard input, in canonical form
+	lint [files...]        report likely mistakes in files or standard input
+	check [files...]       report type errors in files or standard input
+	test [paths...]        run the tests in *_test.mk files, by default ./...
 
-	go func() {
-		<-sigChan
-		fmt.Println("\nExiting gracefully. Goodbye!")
-		done <- true
-	}()
+Imported modules are looked up relative to the importing file, then in the
+directories listed in $MONKEYPATH, or given by the -path flag of run, eval
+and test. Paths starting with std/ name the modules of the standard
+library, which is built into monkey.
 
-	go repl.Start(os.Stdin, os.Stdout)
+Without a command, monkey starts a REPL if standard input is a terminal and
+runs the program read from it otherwise.
+`
 
-	<-done
+// exit codes
+const (
+	exitOK    = 0
+	exitError = 1 // the program failed to parse or evaluate
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		if stdinIsTerminal() {
+			return runREPL(nil)
+		}
+		return runStdin(nil)
+	}
+
+	switch args[0] {
+	case "run":
+		return runScript(args[1:])
+	case "eval":
+		return runEval(args[1:])
+	case "repl":
+		return runREPL(args[1:])
+	case "rpc":
+		return runRPC(args[1:])
+	case "lsp":
+		return runLSP(args[1:])
+	case "fmt":
+		return runFmt(args[1:])
+	case "lint":
+		return runLint(args[1:])
+	case "check":
+		return runCheck(args[1:])
+	case "test":
+		return runTest(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "monkey: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// stdinIsTerminal reports whether standard input is an interactive
+// terminal rather than a file or pipe.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -2,23 +2,66 @@
 
 package object
 
+import (
+	"monkey/ast"
+	"sort"
+	"sync/atomic"
+)
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
-	return env
+	store := make(map[string]Object)
+	return &Environment{store: store, outer: outer, control: outer.control, observer: outer.observer}
 }
 
 func NewEnvironment() *Environment {
 	store := make(map[string]Object)
-	return &Environment{store: store, outer: nil}
+	return &Environment{store: store, outer: nil, control: new(control)}
+}
+
+// NewSlotEnvironment returns an environment enclosed by outer that keeps
+// the values of the names of scope in an array indexed by slot, for a
+// function body whose identifiers have been resolved.
+func NewSlotEnvironment(outer *Environment, scope *ast.Scope) *Environment {
+	return &Environment{
+		slots:    make([]Object, len(scope.Names)),
+		scope:    scope,
+		outer:    outer,
+		control:  outer.control,
+		observer: outer.observer,
+	}
 }
 
+// Environment binds names to values. Global environments, and those of
+// code that has not been resolved, keep them in a map; function calls of
+// resolved code keep them in slots, with a map only for names bound
+// without a slot.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	slots  []Object
+	scope  *ast.Scope // naming the slots
+	outer  *Environment
+	macros *Environment
+
+	// importer loads the modules imported by code evaluated in the
+	// environment, which belongs to file.
+	importer Importer
+	file     string
+
+	// control is shared by an environment and all environments enclosed
+	// by it, so that one flag stops, and one counter measures, every
+	// evaluation of a session.
+	control *control
+
+	// observer, if set, is told about the code evaluated in the
+	// environment. Like control, environments share that of the one
+	// enclosing them.
+	observer Observer
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
+	if slot := e.slot(name); slot >= 0 && e.slots[slot] != nil {
+		return e.slots[slot], true
+	}
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
@@ -27,6 +70,197 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	if slot := e.slot(name); slot >= 0 {
+		e.slots[slot] = val
+		return val
+	}
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// GetAt returns the value in slot of the environment depth levels out from
+// e, if that environment has slots and the slot has been set. The caller
+// must have resolved depth and slot against the scopes the environments
+// were made for.
+func (e *Environment) GetAt(depth, slot int) (Object, bool) {
+	for ; depth > 0 && e != nil; depth-- {
+		e = e.outer
+	}
+	if e == nil || slot >= len(e.slots) || e.slots[slot] == nil {
+		return nil, false
+	}
+	return e.slots[slot], true
+}
+
+// SetAt stores val in slot of e if e has slots, as for GetAt, and binds
+// name as Set does otherwise.
+func (e *Environment) SetAt(slot int, name string, val Object) Object {
+	if slot >= len(e.slots) {
+		return e.Set(name, val)
+	}
+	e.slots[slot] = val
+	return val
+}
+
+// slot returns the slot of name in e, or -1.
+func (e *Environment) slot(name string) int {
+	if e.scope == nil {
+		return -1
+	}
+	if slot, ok := e.scope.Slots[name]; ok {
+		return slot
+	}
+	return -1
+}
+
+// SetMacroEnv records the environment macros for code evaluated in e are
+// defined in, so that they can be expanded at runtime.
+func (e *Environment) SetMacroEnv(macros *Environment) {
+	e.macros = macros
+}
+
+// MacroEnv returns the macro environment of e or of the nearest enclosing
+// environment that has one.
+func (e *Environment) MacroEnv() (*Environment, bool) {
+	for env := e; env != nil; env = env.outer {
+		if env.macros != nil {
+			return env.macros, true
+		}
+	}
+	return nil, false
+}
+
+// Importer loads modules for import statements.
+type Importer interface {
+	// Import returns the module at path, as imported by the file from,
+	// which is empty for code that does not come from a file.
+	Import(path, from string) (*Module, error)
+}
+
+// SetImporter records the importer that loads the modules imported by code
+// evaluated in e, and the file that code belongs to.
+func (e *Environment) SetImporter(importer Importer, file string) {
+	e.importer = importer
+	e.file = file
+}
+
+// Importer returns the importer of e or of the nearest enclosing
+// environment that has one, with the file its code belongs to.
+func (e *Environment) Importer() (Importer, string, bool) {
+	for env := e; env != nil; env = env.outer {
+		if env.importer != nil {
+			return env.importer, env.file, true
+		}
+	}
+	return nil, "", false
+}
+
+// Observer is told about the code evaluated in an environment, for tools
+// such as coverage and profiling.
+type Observer interface {
+	// Statement is called before stmt is evaluated.
+	Statement(stmt ast.Statement)
+	// Branch is called once the condition of node has been evaluated,
+	// with whether its consequence is taken.
+	Branch(node *ast.IfExpression, taken bool)
+	// Enter is called before fn is called from line, and Exit after it
+	// returns.
+	Enter(fn *Function, line int)
+	Exit()
+}
+
+// SetObserver makes the code evaluated in e, and in environments enclosed
+// by e after the call, be reported to o.
+func (e *Environment) SetObserver(o Observer) {
+	e.observer = o
+}
+
+// Observer returns the observer of e, or nil if it has none.
+func (e *Environment) Observer() Observer {
+	return e.observer
+}
+
+// Bindings returns a copy of the names bound directly in e, without those of
+// enclosing environments.
+func (e *Environment) Bindings() map[string]Object {
+	bindings := make(map[string]Object, len(e.store)+len(e.slots))
+	for name, val := range e.store {
+		bindings[name] = val
+	}
+	for i, val := range e.slots {
+		if val != nil {
+			bindings[e.scope.Names[i]] = val
+		}
+	}
+	return bindings
+}
+
+// control holds the state of the evaluations of a session that is read
+// and changed from outside them.
+type control struct {
+	interrupt atomic.Bool
+	steps     atomic.Int64
+}
+
+// ShareControl makes e, and environments enclosed by it afterwards, use the
+// interrupt flag and step count of from, so that interrupting from also
+// stops the evaluations running in e, and their steps count towards those
+// of from.
+func (e *Environment) ShareControl(from *Environment) {
+	e.control = from.control
+}
+
+// Interrupt asks evaluations running in e, and in every environment enclosed
+// by it or sharing its root, to stop as soon as possible.
+func (e *Environment) Interrupt() {
+	e.control.interrupt.Store(true)
+}
+
+// Interrupted reports whether Interrupt has been called since the last
+// ClearInterrupt.
+func (e *Environment) Interrupted() bool {
+	return e.control.interrupt.Load()
+}
+
+// ClearInterrupt allows evaluation in e to run again after an Interrupt.
+func (e *Environment) ClearInterrupt() {
+	e.control.interrupt.Store(false)
+}
+
+// Step counts one step of evaluation in e.
+func (e *Environment) Step() {
+	e.control.steps.Add(1)
+}
+
+// Steps returns the number of steps evaluated in e and the environments
+// sharing its control so far. Tools measure the work done by a piece of
+// code as the difference before and after evaluating it.
+func (e *Environment) Steps() int64 {
+	return e.control.steps.Load()
+}
+
+// Names returns the sorted names visible in e, including those bound in
+// enclosing environments.
+func (e *Environment) Names() []string {
+	seen := make(map[string]bool)
+	for env := e; env != nil; env = env.outer {
+		for name := range env.store {
+			seen[name] = true
+		}
+		for i, val := range env.slots {
+			if val != nil {
+				seen[env.scope.Names[i]] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
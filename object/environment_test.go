@@ -0,0 +1,149 @@
+// object/environment_test.go
+
+package object
+
+import (
+	"monkey/ast"
+	"reflect"
+	"testing"
+)
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("b", &Integer{Value: 1})
+	outer.Set("a", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("c", &Integer{Value: 3})
+	inner.Set("a", &Integer{Value: 4})
+
+	expected := []string{"a", "b", "c"}
+	if names := inner.Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong names. want=%q, got=%q", expected, names)
+	}
+
+	expected = []string{"a", "b"}
+	if names := outer.Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong names. want=%q, got=%q", expected, names)
+	}
+}
+
+// scope returns the scope of a function body binding names.
+func scope(names ...string) *ast.Scope {
+	s := &ast.Scope{Names: names, Slots: make(map[string]int)}
+	for i, name := range names {
+		s.Slots[name] = i
+	}
+	return s
+}
+
+func TestSlotEnvironment(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("g", &Integer{Value: 1})
+
+	outer := NewSlotEnvironment(global, scope("a", "b"))
+	outer.SetAt(0, "a", &Integer{Value: 2})
+	inner := NewSlotEnvironment(outer, scope("c"))
+	inner.SetAt(0, "c", &Integer{Value: 3})
+	inner.Set("d", &Integer{Value: 4}) // a name without a slot
+
+	tests := []struct {
+		depth, slot int
+		expected    int64 // 0 if not found
+	}{
+		{0, 0, 3},
+		{1, 0, 2},
+		{1, 1, 0}, // not set yet
+		{2, 0, 0}, // globals have no slots
+		{3, 0, 0},
+	}
+	for _, tt := range tests {
+		obj, ok := inner.GetAt(tt.depth, tt.slot)
+		if tt.expected == 0 {
+			if ok {
+				t.Errorf("GetAt(%d, %d) found %s", tt.depth, tt.slot, obj.Inspect())
+			}
+			continue
+		}
+		if !ok || obj.(*Integer).Value != tt.expected {
+			t.Errorf("GetAt(%d, %d) wrong. want=%d, got=%v", tt.depth, tt.slot, tt.expected, obj)
+		}
+	}
+
+	for name, expected := range map[string]int64{"a": 2, "c": 3, "d": 4, "g": 1} {
+		obj, ok := inner.Get(name)
+		if !ok || obj.(*Integer).Value != expected {
+			t.Errorf("Get(%q) wrong. want=%d, got=%v", name, expected, obj)
+		}
+	}
+	if _, ok := inner.Get("b"); ok {
+		t.Errorf("Get found a slot that was not set")
+	}
+
+	outer.Set("b", &Integer{Value: 5})
+	if obj, ok := inner.GetAt(1, 1); !ok || obj.(*Integer).Value != 5 {
+		t.Errorf("Set did not fill the slot of b. got=%v", obj)
+	}
+
+	expected := []string{"a", "b", "c", "d", "g"}
+	if names := inner.Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong names. want=%q, got=%q", expected, names)
+	}
+	if bindings := outer.Bindings(); len(bindings) != 2 || bindings["a"] == nil || bindings["b"] == nil {
+		t.Errorf("wrong bindings. got=%v", bindings)
+	}
+}
+
+func TestEnvironmentSteps(t *testing.T) {
+	session := NewEnvironment()
+	other := NewEnvironment()
+	module := NewEnvironment()
+	module.ShareControl(session)
+
+	NewEnclosedEnvironment(session).Step()
+	NewSlotEnvironment(module, scope("x")).Step()
+	other.Step()
+
+	if steps := session.Steps(); steps != 2 {
+		t.Errorf("wrong steps for the session. want=2, got=%d", steps)
+	}
+	if steps := other.Steps(); steps != 1 {
+		t.Errorf("wrong steps for another session. want=1, got=%d", steps)
+	}
+}
+
+// The benchmarks look up the last of eight names, bound in the environment
+// of the function doing the lookup and in that of an enclosing function,
+// by name in map environments and by slot in slot environments.
+
+var benchmarkNames = []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+func BenchmarkMapGet(b *testing.B) {
+	outer := NewEnclosedEnvironment(NewEnvironment())
+	inner := NewEnclosedEnvironment(outer)
+	for _, name := range benchmarkNames {
+		outer.Set(name, &Integer{})
+		inner.Set(name+"1", &Integer{})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inner.Get("h1")
+		inner.Get("h")
+	}
+}
+
+func BenchmarkSlotGetAt(b *testing.B) {
+	outer := NewSlotEnvironment(NewEnvironment(), scope(benchmarkNames...))
+	inner := NewSlotEnvironment(outer, scope(benchmarkNames...))
+	for i := range benchmarkNames {
+		outer.SetAt(i, benchmarkNames[i], &Integer{})
+		inner.SetAt(i, benchmarkNames[i], &Integer{})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inner.GetAt(0, 7)
+		inner.GetAt(1, 7)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"fmt"
 	"monkey/ast"
+	"monkey/utils"
 	"strings"
 	"hash/fnv"
 )
@@ -25,11 +26,16 @@ const (
 	HASH_OBJ		 = "HASH"
 	QUOTE_OBJ		 = "QUOTE"
 	MACRO_OBJ		 = "MACRO"
+	MODULE_OBJ       = "MODULE"
 )
 
+// Object is a Monkey value. Inspect returns its display form, as printed by
+// puts; Repr returns a source-like form that reads back as the same value
+// where possible, as shown by the REPL and inside arrays and hashes.
 type Object interface {
 	Type() ObjectType
 	Inspect() string
+	Repr() string
 }
 
 type Integer struct {
@@ -44,6 +50,10 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+func (i *Integer) Repr() string {
+	return i.Inspect()
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -56,6 +66,10 @@ func (b *Boolean) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
+func (b *Boolean) Repr() string {
+	return b.Inspect()
+}
+
 type String struct {
 	Value string
 }
@@ -68,6 +82,10 @@ func (s *String) Inspect() string {
 	return s.Value
 }
 
+func (s *String) Repr() string {
+	return utils.Quote(s.Value)
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType {
@@ -78,6 +96,10 @@ func (n *Null) Inspect() string {
 	return "null"
 }
 
+func (n *Null) Repr() string {
+	return n.Inspect()
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -90,8 +112,16 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+func (rv *ReturnValue) Repr() string {
+	return rv.Value.Repr()
+}
+
+// Error is a failure that stops evaluation. Line and Column give the
+// position of the expression that failed, if it is known.
 type Error struct {
 	Message string
+	Line    int
+	Column  int
 }
 
 func (e *Error) Type() ObjectType {
@@ -102,10 +132,20 @@ func (e *Error) Inspect() string {
 	return fmt.Sprintf("Error: %s", e.Message)
 }
 
+func (e *Error) Repr() string {
+	return e.Inspect()
+}
+
 type Function struct {
+	// Name is that of the let the function literal is bound by, if any.
+	Name       string
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+
+	// Scope is the resolved scope of the body, if any; calls keep its
+	// names in slots.
+	Scope *ast.Scope
 }
 
 func (f *Function) Type() ObjectType {
@@ -130,6 +170,10 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+func (f *Function) Repr() string {
+	return f.Inspect()
+}
+
 type BuiltinFunction func(args ...Object) Object
 
 type Builtin struct {
@@ -144,6 +188,10 @@ func (b *Builtin) Inspect() string {
 	return "builtin function"
 }
 
+func (b *Builtin) Repr() string {
+	return b.Inspect()
+}
+
 type Array struct {
 	Elements []Object
 }
@@ -157,7 +205,7 @@ func (a *Array) Inspect() string {
 	elements := []string{}
 
 	for _, e := range a.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, e.Repr())
 	}
 	out.WriteString("[")
 	out.WriteString(strings.Join(elements, ", "))
@@ -165,6 +213,10 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+func (a *Array) Repr() string {
+	return a.Inspect()
+}
+
 type Hash struct {
 	Pairs map[HashKey]HashPair
 }
@@ -177,8 +229,8 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
 
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	for _, pair := range sortedPairs(h) {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Repr(), pair.Value.Repr()))
 	}
 
 	out.WriteString("{")
@@ -187,6 +239,10 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+func (h *Hash) Repr() string {
+	return h.Inspect()
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -240,6 +296,10 @@ func (q *Quote) Inspect() string {
 	return out.String()
 }
 
+func (q *Quote) Repr() string {
+	return q.Inspect()
+}
+
 type Macro struct {
 	Parameters []*ast.Identifier
 	Body      *ast.BlockStatement
@@ -268,3 +328,27 @@ func (m *Macro) Inspect() string {
 	return out.String()
 }
 
+func (m *Macro) Repr() string {
+	return m.Inspect()
+}
+
+
+// Module is the namespace an import binds: the values and macros a module
+// file exports, by name.
+type Module struct {
+	Path    string
+	Exports map[string]Object
+	Macros  map[string]*Macro
+}
+
+func (m *Module) Type() ObjectType {
+	return MODULE_OBJ
+}
+
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("<module %s>", utils.Quote(m.Path))
+}
+
+func (m *Module) Repr() string {
+	return m.Inspect()
+}
@@ -21,4 +21,32 @@ func TestStringHashKey(t *testing.T) {
     if hello1.HashKey() == diff1.HashKey() {
         t.Errorf("strings with different content have same hash keys")
     }
-}
\ No newline at end of file
+}
+func TestInspectAndRepr(t *testing.T) {
+	str := &String{Value: "say \"hi\"\n"}
+	array := &Array{Elements: []Object{str, &Integer{Value: 1}, &Null{}}}
+	hash := &Hash{Pairs: map[HashKey]HashPair{
+		str.HashKey(): {Key: str, Value: array},
+	}}
+
+	tests := []struct {
+		obj     Object
+		inspect string
+		repr    string
+	}{
+		{str, "say \"hi\"\n", `"say \"hi\"\n"`},
+		{&String{Value: `a\b`}, `a\b`, `"a\\b"`},
+		{array, `["say \"hi\"\n", 1, null]`, `["say \"hi\"\n", 1, null]`},
+		{hash, `{"say \"hi\"\n": ["say \"hi\"\n", 1, null]}`, `{"say \"hi\"\n": ["say \"hi\"\n", 1, null]}`},
+		{&ReturnValue{Value: str}, "say \"hi\"\n", `"say \"hi\"\n"`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.obj.Inspect(); got != tt.inspect {
+			t.Errorf("Inspect() wrong. want=%q, got=%q", tt.inspect, got)
+		}
+		if got := tt.obj.Repr(); got != tt.repr {
+			t.Errorf("Repr() wrong. want=%q, got=%q", tt.repr, got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+//object/pretty.go
+
+package object
+
+import (
+	"sort"
+	"strings"
+)
+
+// Pretty formats obj for display, quoting strings and breaking arrays and
+// hashes that do not fit in width columns over several indented lines.
+func Pretty(obj Object, width int) string {
+	var out strings.Builder
+	pretty(&out, obj, 0, width)
+	return out.String()
+}
+
+const prettyIndent = "  "
+
+func pretty(out *strings.Builder, obj Object, depth, width int) {
+	flat := prettyFlat(obj)
+	indent := len(prettyIndent) * depth
+	if indent+len(flat) <= width {
+		out.WriteString(flat)
+		return
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		if len(obj.Elements) == 0 {
+			out.WriteString(flat)
+			return
+		}
+		out.WriteString("[\n")
+		for i, e := range obj.Elements {
+			out.WriteString(strings.Repeat(prettyIndent, depth+1))
+			pretty(out, e, depth+1, width)
+			if i < len(obj.Elements)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(strings.Repeat(prettyIndent, depth) + "]")
+	case *Hash:
+		if len(obj.Pairs) == 0 {
+			out.WriteString(flat)
+			return
+		}
+		pairs := sortedPairs(obj)
+		out.WriteString("{\n")
+		for i, pair := range pairs {
+			out.WriteString(strings.Repeat(prettyIndent, depth+1))
+			out.WriteString(prettyFlat(pair.Key) + ": ")
+			pretty(out, pair.Value, depth+1, width)
+			if i < len(pairs)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(strings.Repeat(prettyIndent, depth) + "}")
+	default:
+		out.WriteString(flat)
+	}
+}
+
+// prettyFlat formats obj on a single line.
+func prettyFlat(obj Object) string {
+	switch obj := obj.(type) {
+	case *Array:
+		elements := make([]string, len(obj.Elements))
+		for i, e := range obj.Elements {
+			elements[i] = prettyFlat(e)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *Hash:
+		pairs := sortedPairs(obj)
+		elements := make([]string, len(pairs))
+		for i, pair := range pairs {
+			elements[i] = prettyFlat(pair.Key) + ": " + prettyFlat(pair.Value)
+		}
+		return "{" + strings.Join(elements, ", ") + "}"
+	default:
+		return obj.Repr()
+	}
+}
+
+// sortedPairs returns the pairs of h ordered by the repr of their keys, so
+// that hashes always print the same way.
+func sortedPairs(h *Hash) []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.Repr() < pairs[j].Key.Repr()
+	})
+	return pairs
+}
@@ -0,0 +1,46 @@
+// object/pretty_test.go
+
+package object
+
+import "testing"
+
+func TestPretty(t *testing.T) {
+	str := func(s string) *String { return &String{Value: s} }
+	integer := func(i int64) *Integer { return &Integer{Value: i} }
+	hash := func(pairs ...Object) *Hash {
+		h := &Hash{Pairs: map[HashKey]HashPair{}}
+		for i := 0; i < len(pairs); i += 2 {
+			key := pairs[i].(Hashable).HashKey()
+			h.Pairs[key] = HashPair{Key: pairs[i], Value: pairs[i+1]}
+		}
+		return h
+	}
+
+	tests := []struct {
+		obj      Object
+		width    int
+		expected string
+	}{
+		{str("hi"), 80, `"hi"`},
+		{str("a \"b\"\n"), 80, `"a \"b\"\n"`},
+		{&Array{Elements: []Object{str("a"), integer(1)}}, 80, `["a", 1]`},
+		{hash(str("b"), integer(2), str("a"), integer(1)), 80, `{"a": 1, "b": 2}`},
+		{&Array{Elements: []Object{}}, 1, `[]`},
+		{
+			&Array{Elements: []Object{integer(1), &Array{Elements: []Object{integer(2), integer(3)}}}},
+			10,
+			"[\n  1,\n  [2, 3]\n]",
+		},
+		{
+			hash(str("list"), &Array{Elements: []Object{str("first"), str("second")}}, str("n"), integer(1)),
+			20,
+			"{\n  \"list\": [\n    \"first\",\n    \"second\"\n  ],\n  \"n\": 1\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Pretty(tt.obj, tt.width); got != tt.expected {
+			t.Errorf("Pretty(%s, %d) wrong.\nwant=%q\ngot=%q", tt.obj.Inspect(), tt.width, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// optimize/branch.go
+
+package optimize
+
+import (
+	"monkey/ast"
+	"monkey/token"
+)
+
+// EliminateDeadBranches drops the branch of each if expression whose
+// condition is a literal that never selects it. An if used as a statement
+// is replaced by the statements of the branch that runs, since blocks do
+// not have scopes of their own; one used as a value is replaced by the
+// branch's value if that is a single expression, and by null if no branch
+// runs.
+func EliminateDeadBranches(program *ast.Program) *ast.Program {
+	rewriteStatements(program, spliceLiveBranches)
+	rewrite(program, func(node ast.Node) ast.Node {
+		if ifExp, ok := node.(*ast.IfExpression); ok {
+			return liveExpression(ifExp)
+		}
+		return node
+	})
+	return program
+}
+
+// liveBranch returns the branch of node that runs and true if its
+// condition is a literal, or false otherwise. The branch is nil if none
+// runs.
+func liveBranch(node *ast.IfExpression) (*ast.BlockStatement, bool) {
+	var truthy bool
+	switch cond := node.Condition.(type) {
+	case *ast.Boolean:
+		truthy = cond.Value
+	case *ast.NullLiteral:
+		truthy = false
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		truthy = true
+	default:
+		return nil, false
+	}
+	if truthy {
+		return node.Consequence, true
+	}
+	return node.Alternative, true
+}
+
+// liveExpression returns an expression evaluating to the same value as
+// node, an if that is used as a value.
+func liveExpression(node *ast.IfExpression) ast.Expression {
+	branch, ok := liveBranch(node)
+	switch {
+	case !ok:
+		return node
+	case branch == nil:
+		return &ast.NullLiteral{Token: at(node.Token, token.NULL, "null")}
+	case len(branch.Statements) == 1:
+		if stmt, ok := branch.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+			return stmt.Expression
+		}
+	}
+
+	// Keep the branch in an if of its own, since its statements cannot be
+	// written as one expression.
+	if cond, ok := node.Condition.(*ast.Boolean); ok && cond.Value && node.Alternative == nil {
+		return node
+	}
+	return &ast.IfExpression{
+		Token:       node.Token,
+		Condition:   newBoolean(node.Token, true),
+		Consequence: branch,
+	}
+}
+
+// spliceLiveBranches replaces the ifs used as statements in statements by
+// the statements of the branches that run. The last statement is left
+// alone unless its branch has statements, whose last gives the value of
+// the block just as the if did.
+func spliceLiveBranches(statements []ast.Statement) []ast.Statement {
+	var result []ast.Statement
+	for i, stmt := range statements {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			result = append(result, stmt)
+			continue
+		}
+		ifExp, ok := es.Expression.(*ast.IfExpression)
+		if !ok {
+			result = append(result, stmt)
+			continue
+		}
+		branch, ok := liveBranch(ifExp)
+		last := i == len(statements)-1
+		switch {
+		case !ok, last && (branch == nil || len(branch.Statements) == 0):
+			result = append(result, stmt)
+		case branch != nil:
+			result = append(result, branch.Statements...)
+		}
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+// optimize/fold.go
+
+package optimize
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+)
+
+// FoldConstants replaces the prefix and infix expressions whose operands
+// are integer, string, boolean or null literals with the literal they
+// evaluate to. Expressions that fail when evaluated, such as -"a", are
+// left for the evaluator to report.
+func FoldConstants(program *ast.Program) *ast.Program {
+	rewrite(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.PrefixExpression:
+			if folded := foldPrefix(node); folded != nil {
+				return folded
+			}
+		case *ast.InfixExpression:
+			if folded := foldInfix(node); folded != nil {
+				return folded
+			}
+		}
+		return node
+	})
+	return program
+}
+
+func foldPrefix(node *ast.PrefixExpression) ast.Expression {
+	switch node.Operator {
+	case "!":
+		// ! is true of false and false of everything else, null included.
+		switch right := node.Right.(type) {
+		case *ast.Boolean:
+			return newBoolean(node.Token, !right.Value)
+		case *ast.IntegerLiteral, *ast.StringLiteral, *ast.NullLiteral:
+			return newBoolean(node.Token, false)
+		}
+	case "-":
+		if right, ok := node.Right.(*ast.IntegerLiteral); ok {
+			return newInteger(node.Token, -right.Value)
+		}
+	}
+	return nil
+}
+
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	// The result takes the position of the left operand, where the
+	// expression starts.
+	switch left := node.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := node.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+		return foldIntegers(left, node.Operator, right)
+	case *ast.StringLiteral:
+		right, ok := node.Right.(*ast.StringLiteral)
+		if !ok || node.Operator != "+" {
+			return nil
+		}
+		return newString(left.Token, left.Value+right.Value)
+	case *ast.Boolean:
+		right, ok := node.Right.(*ast.Boolean)
+		if !ok {
+			return nil
+		}
+		switch node.Operator {
+		case "==":
+			return newBoolean(left.Token, left.Value == right.Value)
+		case "!=":
+			return newBoolean(left.Token, left.Value != right.Value)
+		}
+	}
+	return nil
+}
+
+func foldIntegers(left *ast.IntegerLiteral, operator string, right *ast.IntegerLiteral) ast.Expression {
+	l, r := left.Value, right.Value
+	switch operator {
+	case "+":
+		return newInteger(left.Token, l+r)
+	case "-":
+		return newInteger(left.Token, l-r)
+	case "*":
+		return newInteger(left.Token, l*r)
+	case "/":
+		if r == 0 {
+			// Division by zero evaluates to null.
+			return &ast.NullLiteral{Token: at(left.Token, token.NULL, "null")}
+		}
+		return newInteger(left.Token, l/r)
+	case "<":
+		return newBoolean(left.Token, l < r)
+	case ">":
+		return newBoolean(left.Token, l > r)
+	case "==":
+		return newBoolean(left.Token, l == r)
+	case "!=":
+		return newBoolean(left.Token, l != r)
+	}
+	return nil
+}
+
+// at returns a token of type t with literal lit at the position of pos.
+func at(pos token.Token, t token.TokenType, lit string) token.Token {
+	return token.Token{Type: t, Literal: lit, Line: pos.Line, Column: pos.Column}
+}
+
+func newInteger(pos token.Token, value int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{Token: at(pos, token.INT, strconv.FormatInt(value, 10)), Value: value}
+}
+
+func newString(pos token.Token, value string) *ast.StringLiteral {
+	return &ast.StringLiteral{Token: at(pos, token.STRING, value), Value: value}
+}
+
+func newBoolean(pos token.Token, value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: at(pos, token.TRUE, "true"), Value: true}
+	}
+	return &ast.Boolean{Token: at(pos, token.FALSE, "false"), Value: false}
+}
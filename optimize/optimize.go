@@ -0,0 +1,89 @@
+// optimize/optimize.go
+
+// Package optimize rewrites Monkey programs into ones that evaluate to the
+// same result with less work: constant expressions are folded, branches
+// that can never run are dropped and unused lets of pure values are
+// removed. Each pass takes a program, rewrites it in place and returns it.
+//
+// The arguments of quote calls are left alone, since they are code as
+// data rather than code that runs.
+package optimize
+
+import (
+	"bytes"
+	"monkey/ast"
+)
+
+// Pass is an optimization pass.
+type Pass func(program *ast.Program) *ast.Program
+
+// Passes are the passes Optimize makes by default, in order.
+var Passes = []Pass{FoldConstants, EliminateDeadBranches, RemoveUnusedLets}
+
+// maxRounds bounds how many times Optimize runs its passes.
+const maxRounds = 16
+
+// Optimize runs passes, or Passes if there are none, over program until
+// they no longer change it, since one pass can make room for another: a
+// folded condition lets a branch be dropped, and dropping the branch can
+// leave a let unused.
+func Optimize(program *ast.Program, passes ...Pass) *ast.Program {
+	if len(passes) == 0 {
+		passes = Passes
+	}
+
+	before := fingerprint(program)
+	for round := 0; round < maxRounds; round++ {
+		for _, pass := range passes {
+			program = pass(program)
+		}
+		after := fingerprint(program)
+		if bytes.Equal(before, after) {
+			break
+		}
+		before = after
+	}
+	return program
+}
+
+// fingerprint returns a description of program that changes whenever the
+// program does.
+func fingerprint(program *ast.Program) []byte {
+	data, _ := ast.MarshalJSON(program)
+	return data
+}
+
+// isQuote reports whether node is a call to quote, whose arguments are not
+// evaluated.
+func isQuote(node ast.Node) bool {
+	call, ok := node.(*ast.CallExpression)
+	return ok && call.Function.TokenLiteral() == "quote"
+}
+
+// rewrite replaces every node of the tree rooted at node with the result of
+// calling fn on it, children before parents, except inside quote calls.
+func rewrite(node ast.Node, fn ast.ModifyFunc) ast.Node {
+	if isQuote(node) {
+		return node
+	}
+	ast.ModifyChildren(node, func(child ast.Node) ast.Node {
+		return rewrite(child, fn)
+	})
+	return fn(node)
+}
+
+// rewriteStatements replaces the statements of the program and of every
+// block in it with the result of calling fn on them, inner blocks first,
+// except inside quote calls.
+func rewriteStatements(program *ast.Program, fn func([]ast.Statement) []ast.Statement) *ast.Program {
+	rewrite(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.Program:
+			node.Statements = fn(node.Statements)
+		case *ast.BlockStatement:
+			node.Statements = fn(node.Statements)
+		}
+		return node
+	})
+	return program
+}
@@ -0,0 +1,150 @@
+// optimize/optimize_test.go
+
+package optimize
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/format"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func formatted(t *testing.T, program *ast.Program) string {
+	t.Helper()
+	var out strings.Builder
+	if err := format.Node(&out, program); err != nil {
+		t.Fatalf("format.Node returned %v", err)
+	}
+	return out.String()
+}
+
+func testPass(t *testing.T, pass Pass, input, expected string) {
+	t.Helper()
+	got := formatted(t, pass(parse(t, input)))
+	want := formatted(t, parse(t, expected))
+	if got != want {
+		t.Errorf("wrong result for %q.\nwant=%q\ngot= %q", input, want, got)
+	}
+}
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(10 - 4) / 2 < 4", "true"},
+		{"-(2 * 3)", "-6"},
+		{"5 / 0", "null"},
+		{`"foo" + "bar" + "baz"`, `"foobarbaz"`},
+		{"!true; !false; !5; !null", "false; true; false; false"},
+		{"true == (1 < 2); false != false", "true; false"},
+		{"x + 1 * 2", "x + 2"},
+		{"fn(a) { a * (2 + 3) }", "fn(a) { a * 5 }"},
+		{`"a" == "a"; -"a"; 1 + "a"`, `"a" == "a"; -"a"; 1 + "a"`},
+		{"quote(1 + 2); unquote(1 + 2)", "quote(1 + 2); unquote(3)"},
+	}
+
+	for _, tt := range tests {
+		testPass(t, FoldConstants, tt.input, tt.expected)
+	}
+}
+
+func TestEliminateDeadBranches(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = if (true) { 1 } else { 2 };", "let x = 1;"},
+		{"let x = if (false) { 1 } else { 2 };", "let x = 2;"},
+		{"let x = if (null) { 1 };", "let x = null;"},
+		{"let x = if (0) { 1 };", "let x = 1;"},
+		{"let x = if (c) { 1 } else { 2 };", "let x = if (c) { 1 } else { 2 };"},
+		{
+			"let x = if (false) { 1 } else { puts(2); 3 };",
+			"let x = if (true) { puts(2); 3 };",
+		},
+		{"if (true) { let a = 1; puts(a) }; a", "let a = 1; puts(a); a"},
+		{"if (false) { puts(1) }; 2", "2"},
+		{"1; if (false) { 2 }", "1; null"},
+		{"1; if (true) { }", "1; if (true) { }"},
+		{
+			"fn() { if (true) { return 1 } else { return 2 }; 3 }",
+			"fn() { return 1; 3 }",
+		},
+		{"quote(if (true) { 1 })", "quote(if (true) { 1 })"},
+	}
+
+	for _, tt := range tests {
+		testPass(t, EliminateDeadBranches, tt.input, tt.expected)
+	}
+}
+
+func TestRemoveUnusedLets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1; let b = 2; b", "let b = 2; b"},
+		{"let xs = [1, {\"a\": fn() { 1 }}, \"s\"]; 0", "0"},
+		{"let a = puts(1); let b = x; let c = {[1]: 2}; 0", "let a = puts(1); let b = x; let c = {[1]: 2}; 0"},
+		{"let a = 1; let f = fn(a) { a }; f(2)", "let a = 1; let f = fn(a) { a }; f(2)"},
+		{"let a = 1; quote(a); 0", "let a = 1; quote(a); 0"},
+		{"let f = fn() { let unused = 1; 2 }; f()", "let f = fn() { 2 }; f()"},
+		{"export let a = 1; let b = 2; 3", "export let a = 1; 3"},
+		{"fn() { let last = 1 }", "fn() { let last = 1 }"},
+	}
+
+	for _, tt := range tests {
+		testPass(t, RemoveUnusedLets, tt.input, tt.expected)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	input := `
+let log = fn(msg) { puts(msg) };
+let area = fn(r) { 3 * r * r + 0 * (10 - 4) };
+if (1 > 2) { log("area") };
+area(2 + 3)`
+
+	expected := `let area = fn(r) { 3 * r * r + 0 };
+area(5)`
+
+	got := formatted(t, Optimize(parse(t, input)))
+	want := formatted(t, parse(t, expected))
+	if got != want {
+		t.Errorf("wrong result.\nwant=%q\ngot= %q", want, got)
+	}
+}
+
+func TestOptimizedProgramsEvaluateTheSame(t *testing.T) {
+	inputs := []string{
+		`let a = 2 * 3; let f = fn(x) { if (true) { x + a } else { x - a } }; f(1)`,
+		`let r = if (1 < 2) { "lt" } else { "ge" }; r + "!"`,
+		`let g = fn() { if (false) { return 1 }; let unused = [1]; 2 }; g()`,
+		`let xs = [1 + 1, 10 / 0]; xs`,
+		`if (!true) { 1 }`,
+	}
+
+	for _, input := range inputs {
+		want := evaluator.Eval(parse(t, input), object.NewEnvironment())
+		got := evaluator.Eval(Optimize(parse(t, input)), object.NewEnvironment())
+		if got.Inspect() != want.Inspect() {
+			t.Errorf("wrong result for %q. want=%s, got=%s", input, want.Inspect(), got.Inspect())
+		}
+	}
+}
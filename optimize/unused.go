@@ -0,0 +1,88 @@
+// optimize/unused.go
+
+package optimize
+
+import "monkey/ast"
+
+// RemoveUnusedLets removes the let statements that bind a name nothing in
+// the program refers to, if evaluating their value cannot fail or have an
+// effect. Exported lets are kept, since other files may use them. Names are
+// matched by spelling alone, so a name used anywhere, even in another scope
+// or in quoted code, keeps every let of it. The last statement of a block or
+// of the program is kept, since it gives its value.
+//
+// The program is taken to be complete: its globals are not kept for code
+// run after it, as in a REPL session.
+func RemoveUnusedLets(program *ast.Program) *ast.Program {
+	used := usedNames(program)
+	return rewriteStatements(program, func(statements []ast.Statement) []ast.Statement {
+		var result []ast.Statement
+		for i, stmt := range statements {
+			let, ok := stmt.(*ast.LetStatement)
+			if ok && i < len(statements)-1 && !let.Exported && !used[let.Name.Value] && pure(let.Value) {
+				continue
+			}
+			result = append(result, stmt)
+		}
+		return result
+	})
+}
+
+// usedNames returns the names of the identifiers in program other than
+// those that let and import statements and parameters bind.
+func usedNames(program *ast.Program) map[string]bool {
+	binding := make(map[*ast.Identifier]bool)
+	used := make(map[string]bool)
+	ast.Walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			binding[node.Name] = true
+		case *ast.ImportStatement:
+			binding[node.Name] = true
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				binding[param] = true
+			}
+		case *ast.MacroLiteral:
+			for _, param := range node.Parameters {
+				binding[param] = true
+			}
+		case *ast.Identifier:
+			if !binding[node] {
+				used[node.Value] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// pure reports whether evaluating e always succeeds without an effect.
+// Function literals are, since their bodies do not run until called.
+func pure(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.NullLiteral, *ast.FunctionLiteral:
+		return true
+	case *ast.ArrayLiteral:
+		for _, elem := range e.Elements {
+			if !pure(elem) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		for key, value := range e.Pairs {
+			switch key.(type) {
+			case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+			default:
+				// Other keys may not be hashable.
+				return false
+			}
+			if !pure(value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -16,14 +16,14 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
-	errors []string
+	diagnostics []Diagnostic
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l}
 	p.nextToken() // Initialize curToken and peekToken
 	p.nextToken()
 
@@ -34,6 +34,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
@@ -56,13 +57,41 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// Diagnostic is a syntax error and the position of the token it was found
+// at.
+type Diagnostic struct {
+	Line, Column int
+	Message      string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// Errors returns the messages of the syntax errors found so far.
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := []string{}
+	for _, d := range p.diagnostics {
+		errors = append(errors, d.Message)
+	}
+	return errors
+}
+
+// Diagnostics returns the syntax errors found so far with their positions.
+func (p *Parser) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+func (p *Parser) errorAt(tok token.Token, format string, args ...interface{}) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) nextToken() {
@@ -87,7 +116,26 @@ func (p *Parser) ParseProgram() *ast.Program {
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		// Return an untyped nil rather than a nil *ast.LetStatement, which
+		// would not compare equal to nil.
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.EXPORT:
+		if !p.expectPeek(token.LET) {
+			return nil
+		}
+		if stmt := p.parseLetStatement(); stmt != nil {
+			stmt.Exported = true
+			return stmt
+		}
+		return nil
+	case token.IMPORT:
+		if stmt := p.parseImportStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return p.parseReturnStatement()
 	default:
@@ -95,6 +143,33 @@ func (p *Parser) parseStatement() ast.Statement {
 	}
 }
 
+// parseImportStatement parses import "path" as name. as is not a keyword,
+// so that it can still be used as a name elsewhere.
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.peekTokenIs(token.IDENT) || p.peekToken.Literal != "as" {
+		p.errorAt(p.peekToken, "expected next token to be as, got %s instead", p.peekToken.Type)
+		return nil
+	}
+	p.nextToken()
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
@@ -103,6 +178,9 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.parseAnnotation(stmt.Name) {
+		return nil
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -218,8 +296,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -228,8 +305,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, "no prefix parse function for %s found", t)
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -292,6 +368,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.Rbrace = p.curToken
 
 	return block
 }
@@ -309,6 +386,15 @@ var precedenceMap = map[token.TokenType]int{
 	token.LBRACKET: INDEX,
 }
 
+// Precedence returns how tightly the infix operator t binds, or LOWEST if t
+// is not an infix operator.
+func Precedence(t token.TokenType) int {
+	if prec, ok := precedenceMap[t]; ok {
+		return prec
+	}
+	return LOWEST
+}
+
 func (p *Parser) peekPrecedence() int {
 	if prec, ok := precedenceMap[p.peekToken.Type]; ok {
 		return prec
@@ -341,6 +427,10 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	fn := &ast.FunctionLiteral{Token: p.curToken}
 
@@ -350,6 +440,14 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 	fn.Parameters = p.parseFunctionParameters()
 
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		if fn.ReturnType = p.parseType(); fn.ReturnType == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -369,12 +467,18 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 	p.nextToken()
 	param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.parseAnnotation(param) {
+		return nil
+	}
 	params = append(params, param)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.parseAnnotation(param) {
+			return nil
+		}
 		params = append(params, param)
 	}
 
@@ -385,6 +489,81 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return params
 }
 
+// parseAnnotation parses the ": type" that may follow the name ident of a
+// let or parameter. It reports whether there was no annotation or a valid
+// one.
+func (p *Parser) parseAnnotation(ident *ast.Identifier) bool {
+	if !p.peekTokenIs(token.COLON) {
+		return true
+	}
+	p.nextToken()
+	p.nextToken() // consume ':'
+	ident.Type = p.parseType()
+	return ident.Type != nil
+}
+
+// parseType parses a type annotation: a name such as int, [element],
+// {key: value} or fn(parameters) -> result.
+func (p *Parser) parseType() ast.TypeExpression {
+	switch p.curToken.Type {
+	case token.IDENT, token.NULL:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	case token.LBRACKET:
+		t := &ast.ArrayType{Token: p.curToken}
+		p.nextToken()
+		if t.Element = p.parseType(); t.Element == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return t
+	case token.LBRACE:
+		t := &ast.HashType{Token: p.curToken}
+		p.nextToken()
+		if t.Key = p.parseType(); t.Key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		if t.Value = p.parseType(); t.Value == nil || !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		return t
+	case token.FUNCTION:
+		return p.parseFunctionType()
+	default:
+		p.errorAt(p.curToken, "expected a type, got %s instead", p.curToken.Type)
+		return nil
+	}
+}
+
+func (p *Parser) parseFunctionType() ast.TypeExpression {
+	t := &ast.FunctionType{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	t.Parameters = []ast.TypeExpression{}
+	for !p.peekTokenIs(token.RPAREN) {
+		if len(t.Parameters) > 0 && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+		param := p.parseType()
+		if param == nil {
+			return nil
+		}
+		t.Parameters = append(t.Parameters, param)
+	}
+	p.nextToken() // the ')'
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	if t.Result = p.parseType(); t.Result == nil {
+		return nil
+	}
+	return t
+}
+
 func (p *Parser) parseCallExpression(expression ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{
 		Token:    p.curToken,
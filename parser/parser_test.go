@@ -523,6 +523,28 @@ func TestBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestNullLiteralExpression(t *testing.T) {
+	l := lexer.New("null;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if _, ok := stmt.Expression.(*ast.NullLiteral); !ok {
+		t.Fatalf("exp not *ast.NullLiteral. got=%T", stmt.Expression)
+	}
+}
+
 // parser/parser_test.go
 
 func TestIfExpression(t *testing.T) {
@@ -957,4 +979,167 @@ func TestMacroLiteralParsing(t *testing.T) {
     }
 
     testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
-}
\ No newline at end of file
+}
+func TestDiagnostics(t *testing.T) {
+	input := `let x 5;
+let = 10;
+99999999999999999999;`
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	expected := []string{
+		"1:7: expected next token to be =, got INT instead",
+		"2:5: expected next token to be IDENT, got = instead",
+		"2:5: no prefix parse function for = found",
+		`3:1: could not parse "99999999999999999999" as integer`,
+	}
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("wrong number of diagnostics. want=%d, got=%d (%v)", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("diagnostics[%d] wrong. want=%q, got=%q", i, expected[i], d.String())
+		}
+	}
+}
+
+func TestReservedNull(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let null = 1;", "1:5: expected next token to be IDENT, got NULL instead"},
+		{"let null: int = 1;", "1:5: expected next token to be IDENT, got NULL instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 {
+			t.Errorf("%q: expected a diagnostic, got none", tt.input)
+			continue
+		}
+		if diagnostics[0].String() != tt.expected {
+			t.Errorf("%q: diagnostic wrong. want=%q, got=%q", tt.input, tt.expected, diagnostics[0].String())
+		}
+	}
+}
+
+func TestInvalidLetStatementsAreDropped(t *testing.T) {
+	p := New(lexer.New(`let = 1; let x = 2;`))
+	program := p.ParseProgram()
+
+	for i, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let == nil {
+			t.Errorf("program.Statements[%d] is a nil *ast.LetStatement", i)
+		}
+	}
+}
+
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let xs: [string] = [];", "let xs: [string] = [];"},
+		{"let h: {string: [int]} = {};", "let h: {string: [int]} = {};"},
+		{"let f: fn(int, bool) -> null = g;", "let f: fn(int, bool) -> null = g;"},
+		{"let f: fn() -> fn(any) -> int = g;", "let f: fn() -> fn(any) -> int = g;"},
+		{"fn(a: string, b: [int]) -> bool { a }", "fn(a: string, b: [int]) -> bool a"},
+		{"fn(a, b: int) { a }", "fn(a, b: int) a"},
+		{"fn() -> int { 1 }", "fn() -> int 1"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestTypeAnnotationDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: = 5;", "1:8: expected a type, got = instead"},
+		{"let x: [int = 5;", "1:13: expected next token to be ], got = instead"},
+		{"let f: fn(int) = g;", "1:16: expected next token to be ->, got = instead"},
+		{"fn(a: 1) { a }", "1:7: expected a type, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 || diagnostics[0].String() != tt.expected {
+			t.Errorf("wrong diagnostics for %q. want first=%q, got=%v", tt.input, tt.expected, diagnostics)
+		}
+	}
+}
+
+func TestImportAndExportParsing(t *testing.T) {
+	input := `import "lib/math.mk" as math;
+export let square = fn(x) { math["mul"](x, x) };
+let as = 1;`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+
+	imp, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+	if imp.Path.Value != "lib/math.mk" || imp.Name.Value != "math" {
+		t.Errorf("wrong import. got=%q", imp.String())
+	}
+
+	let, ok := program.Statements[1].(*ast.LetStatement)
+	if !ok || !let.Exported || let.Name.Value != "square" {
+		t.Errorf("program.Statements[1] is not an exported let of square. got=%q", program.Statements[1].String())
+	}
+	if let, ok := program.Statements[2].(*ast.LetStatement); !ok || let.Exported {
+		t.Errorf("program.Statements[2] is not an unexported let. got=%q", program.Statements[2].String())
+	}
+
+	expected := `import "lib/math.mk" as math;export let square = fn(x) (math[mul])(x, x);let as = 1;`
+	if program.String() != expected {
+		t.Errorf("wrong program. want=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestImportAndExportDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import lib as lib;`, "1:8: expected next token to be STRING, got IDENT instead"},
+		{`import "lib.mk";`, "1:16: expected next token to be as, got ; instead"},
+		{`import "lib.mk" as;`, "1:19: expected next token to be IDENT, got ; instead"},
+		{`export fn() {};`, "1:8: expected next token to be LET, got FUNCTION instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 || diagnostics[0].String() != tt.expected {
+			t.Errorf("wrong diagnostics for %q. want first=%q, got=%v", tt.input, tt.expected, diagnostics)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// profiler/profiler.go
+
+// Package profiler measures where Monkey programs spend their time and
+// allocate memory, by Monkey function and call site rather than by the Go
+// functions of the evaluator, and writes what it measured as a pprof
+// profile, which go tool pprof reads.
+//
+// A Profiler either times every call, or samples the stack of calls at an
+// interval, which slows the program down less but is less precise. The
+// evaluator tells it of each call of a function and of its return.
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Function identifies a Monkey function: the name of the let that binds
+// it, and where it is defined.
+type Function struct {
+	Name string
+	File string
+	Line int
+}
+
+// Main is the function the code outside of any function is counted in.
+var Main = Function{Name: "main"}
+
+// Profiler records the calls of a program.
+type Profiler struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	root    *node
+	stack   []*frame
+	start   time.Time
+	elapsed time.Duration
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// node counts what was measured of a stack of calls: the calls to fn from
+// the line callLine of its caller, the stack of its parent.
+type node struct {
+	fn       Function
+	callLine int
+	parent   *node
+	children map[call]*node
+
+	// count is the number of calls when timing every call, and of
+	// samples when sampling. nanos and bytes are the time spent and the
+	// memory allocated in fn itself, without the functions it called.
+	count int64
+	nanos int64
+	bytes int64
+}
+
+type call struct {
+	fn   Function
+	line int
+}
+
+// frame is a call in progress.
+type frame struct {
+	node       *node
+	start      time.Time
+	startBytes uint64
+	childNanos int64
+	childBytes int64
+}
+
+// New returns a profiler that samples the stack every interval, or times
+// every call if interval is zero.
+func New(interval time.Duration) *Profiler {
+	return &Profiler{
+		interval: interval,
+		root:     &node{fn: Main},
+	}
+}
+
+// Start starts measuring, from within Main.
+func (p *Profiler) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.start = time.Now()
+	p.stack = []*frame{{node: p.root, start: p.start, startBytes: allocated()}}
+	if p.interval > 0 {
+		p.stop = make(chan struct{})
+		p.done = make(chan struct{})
+		go p.sample(p.stack[0].startBytes)
+	}
+}
+
+// Stop stops measuring. The calls that have not returned are counted up to
+// now.
+func (p *Profiler) Stop() {
+	if p.interval > 0 {
+		close(p.stop)
+		<-p.done
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.stack) > 0 {
+		p.exit()
+	}
+	p.elapsed = time.Since(p.start)
+}
+
+// Enter records a call to fn from the line callLine of the function that
+// is running.
+func (p *Profiler) Enter(fn Function, callLine int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parent := p.stack[len(p.stack)-1].node
+	key := call{fn: fn, line: callLine}
+	n, ok := parent.children[key]
+	if !ok {
+		n = &node{fn: fn, callLine: callLine, parent: parent}
+		if parent.children == nil {
+			parent.children = make(map[call]*node)
+		}
+		parent.children[key] = n
+	}
+
+	f := &frame{node: n}
+	if p.interval == 0 {
+		f.start = time.Now()
+		f.startBytes = allocated()
+	}
+	p.stack = append(p.stack, f)
+}
+
+// Exit records that the function entered last returned.
+func (p *Profiler) Exit() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.stack) > 1 {
+		p.exit()
+	}
+}
+
+func (p *Profiler) exit() {
+	f := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	if p.interval > 0 {
+		return
+	}
+
+	nanos := int64(time.Since(f.start))
+	bytes := int64(allocated() - f.startBytes)
+	f.node.count++
+	f.node.nanos += nanos - f.childNanos
+	f.node.bytes += bytes - f.childBytes
+	if len(p.stack) > 0 {
+		parent := p.stack[len(p.stack)-1]
+		parent.childNanos += nanos
+		parent.childBytes += bytes
+	}
+}
+
+// sample counts the function running at every tick, with the time and the
+// memory allocated since the last.
+func (p *Profiler) sample(lastBytes uint64) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			bytes := allocated()
+			p.mu.Lock()
+			n := p.stack[len(p.stack)-1].node
+			n.count++
+			n.nanos += int64(now.Sub(last))
+			n.bytes += int64(bytes - lastBytes)
+			p.mu.Unlock()
+			last, lastBytes = now, bytes
+		}
+	}
+}
+
+// allocated returns the number of bytes allocated on the heap so far.
+func allocated() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// WriteProfile writes what was measured to w as a gzipped pprof profile.
+// Each sample is a stack of calls, with the count of calls or samples and
+// the time spent and memory allocated in the function on top.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b := newBuilder()
+	countType := "calls"
+	if p.interval > 0 {
+		countType = "samples"
+	}
+	b.sampleType(countType, "count")
+	b.sampleType("time", "nanoseconds")
+	b.sampleType("alloc_space", "bytes")
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.count != 0 || n.nanos != 0 || n.bytes != 0 {
+			b.sample(n, []int64{n.count, n.nanos, n.bytes})
+		}
+		children := make([]*node, 0, len(n.children))
+		for _, child := range n.children {
+			children = append(children, child)
+		}
+		sort.Slice(children, func(i, j int) bool {
+			a, b := children[i], children[j]
+			if a.fn != b.fn {
+				return a.fn.Name < b.fn.Name || a.fn.Name == b.fn.Name &&
+					(a.fn.File < b.fn.File || a.fn.File == b.fn.File && a.fn.Line < b.fn.Line)
+			}
+			return a.callLine < b.callLine
+		})
+		for _, child := range children {
+			walk(child)
+		}
+	}
+	walk(p.root)
+
+	data := b.encode(p.start, p.elapsed, p.interval)
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// builder collects the tables of a pprof profile.
+type builder struct {
+	strings     []string
+	stringIDs   map[string]int64
+	functions   []Function
+	functionIDs map[Function]uint64
+	locations   []location
+	locationIDs map[location]uint64
+	sampleTypes [][2]int64
+	samples     []sample
+}
+
+type location struct {
+	function uint64
+	line     int
+}
+
+type sample struct {
+	locations []int64
+	values    []int64
+}
+
+func newBuilder() *builder {
+	return &builder{
+		strings:     []string{""},
+		stringIDs:   map[string]int64{"": 0},
+		functionIDs: make(map[Function]uint64),
+		locationIDs: make(map[location]uint64),
+	}
+}
+
+func (b *builder) string(s string) int64 {
+	if id, ok := b.stringIDs[s]; ok {
+		return id
+	}
+	id := int64(len(b.strings))
+	b.strings = append(b.strings, s)
+	b.stringIDs[s] = id
+	return id
+}
+
+func (b *builder) sampleType(typ, unit string) {
+	b.sampleTypes = append(b.sampleTypes, [2]int64{b.string(typ), b.string(unit)})
+}
+
+func (b *builder) location(fn Function, line int) int64 {
+	fid, ok := b.functionIDs[fn]
+	if !ok {
+		fid = uint64(len(b.functions) + 1)
+		b.functions = append(b.functions, fn)
+		b.functionIDs[fn] = fid
+		b.string(fn.Name)
+		b.string(fn.File)
+	}
+	loc := location{function: fid, line: line}
+	lid, ok := b.locationIDs[loc]
+	if !ok {
+		lid = uint64(len(b.locations) + 1)
+		b.locations = append(b.locations, loc)
+		b.locationIDs[loc] = lid
+	}
+	return int64(lid)
+}
+
+// sample adds a sample for the stack of n: the function of n where it is
+// defined, then each caller at the line of its call.
+func (b *builder) sample(n *node, values []int64) {
+	locations := []int64{b.location(n.fn, n.fn.Line)}
+	for ; n.parent != nil; n = n.parent {
+		locations = append(locations, b.location(n.parent.fn, n.callLine))
+	}
+	b.samples = append(b.samples, sample{locations: locations, values: values})
+}
+
+// encode returns the profile in the wire format of profile.proto.
+func (b *builder) encode(start time.Time, elapsed, interval time.Duration) []byte {
+	timeType := [2]int64{b.string("time"), b.string("nanoseconds")}
+
+	var e encoder
+	for _, st := range b.sampleTypes {
+		e.message(1, func(e *encoder) {
+			e.int64(1, st[0])
+			e.int64(2, st[1])
+		})
+	}
+	for _, s := range b.samples {
+		e.message(2, func(e *encoder) {
+			e.packed(1, s.locations)
+			e.packed(2, s.values)
+		})
+	}
+	for i, loc := range b.locations {
+		e.message(4, func(e *encoder) {
+			e.uint64(1, uint64(i+1))
+			e.message(4, func(e *encoder) {
+				e.uint64(1, loc.function)
+				e.int64(2, int64(loc.line))
+			})
+		})
+	}
+	for i, fn := range b.functions {
+		e.message(5, func(e *encoder) {
+			e.uint64(1, uint64(i+1))
+			e.int64(2, b.stringIDs[fn.Name])
+			e.int64(3, b.stringIDs[fn.Name])
+			e.int64(4, b.stringIDs[fn.File])
+			e.int64(5, int64(fn.Line))
+		})
+	}
+	for _, s := range b.strings {
+		e.string(6, s)
+	}
+	e.int64(9, start.UnixNano())
+	e.int64(10, int64(elapsed))
+	e.message(11, func(e *encoder) {
+		e.int64(1, timeType[0])
+		e.int64(2, timeType[1])
+	})
+	e.int64(12, int64(interval))
+	e.int64(14, timeType[0])
+	return e.data
+}
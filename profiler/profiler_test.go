@@ -0,0 +1,205 @@
+// profiler/profiler_test.go
+
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+)
+
+// field is a field of a protocol buffer message, as decoded by fields.
+type field struct {
+	num   int
+	value uint64
+	bytes []byte
+}
+
+// varint decodes the varint at the start of *data and advances past it.
+func varint(t *testing.T, data *[]byte) uint64 {
+	t.Helper()
+	var x uint64
+	for shift := 0; ; shift += 7 {
+		if len(*data) == 0 {
+			t.Fatal("truncated varint")
+		}
+		b := (*data)[0]
+		*data = (*data)[1:]
+		x |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return x
+		}
+	}
+}
+
+// fields decodes the fields of the message data, as far as encoder writes
+// them.
+func fields(t *testing.T, data []byte) []field {
+	t.Helper()
+	var out []field
+	for len(data) > 0 {
+		key := varint(t, &data)
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			f.value = varint(t, &data)
+		case wireBytes:
+			n := varint(t, &data)
+			f.bytes, data = data[:n], data[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// packed decodes a packed repeated field.
+func packed(t *testing.T, data []byte) []int64 {
+	t.Helper()
+	var out []int64
+	for len(data) > 0 {
+		out = append(out, int64(varint(t, &data)))
+	}
+	return out
+}
+
+// decoded is what a test needs of a profile: its samples, as the names of
+// the functions of their stacks, leaf first, and their values.
+type decoded struct {
+	sampleTypes []string
+	stacks      []string
+	values      [][]int64
+}
+
+func decode(t *testing.T, p *Profiler) decoded {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.WriteProfile(&buf); err != nil {
+		t.Fatalf("WriteProfile failed: %s", err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("profile is not gzipped: %s", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var strs []string
+	functions := make(map[uint64]uint64) // function ID -> name
+	locations := make(map[uint64]uint64) // location ID -> function ID
+	var sampleTypes [][]field
+	var samples [][]field
+	for _, f := range fields(t, data) {
+		switch f.num {
+		case 1:
+			sampleTypes = append(sampleTypes, fields(t, f.bytes))
+		case 2:
+			samples = append(samples, fields(t, f.bytes))
+		case 4:
+			var id, fn uint64
+			for _, lf := range fields(t, f.bytes) {
+				switch lf.num {
+				case 1:
+					id = lf.value
+				case 4:
+					fn = fields(t, lf.bytes)[0].value
+				}
+			}
+			locations[id] = fn
+		case 5:
+			fs := fields(t, f.bytes)
+			functions[fs[0].value] = fs[1].value
+		case 6:
+			strs = append(strs, string(f.bytes))
+		}
+	}
+
+	var d decoded
+	for _, st := range sampleTypes {
+		d.sampleTypes = append(d.sampleTypes, strs[st[0].value]+"/"+strs[st[1].value])
+	}
+	for _, s := range samples {
+		var stack string
+		for _, loc := range packed(t, s[0].bytes) {
+			if stack != "" {
+				stack += " < "
+			}
+			stack += strs[functions[locations[uint64(loc)]]]
+		}
+		d.stacks = append(d.stacks, stack)
+		d.values = append(d.values, packed(t, s[1].bytes))
+	}
+	return d
+}
+
+func TestInstrumenting(t *testing.T) {
+	p := New(0)
+	p.Start()
+	f := Function{Name: "f", File: "a.mk", Line: 1}
+	g := Function{Name: "g", File: "a.mk", Line: 4}
+	for i := 0; i < 3; i++ {
+		p.Enter(f, 7)
+		p.Enter(g, 2)
+		time.Sleep(time.Millisecond)
+		p.Exit()
+		p.Exit()
+	}
+	p.Enter(g, 8)
+	p.Stop() // counts the call of g that did not return
+
+	d := decode(t, p)
+	expectedTypes := []string{"calls/count", "time/nanoseconds", "alloc_space/bytes"}
+	if len(d.sampleTypes) != len(expectedTypes) {
+		t.Fatalf("wrong sample types. want=%v, got=%v", expectedTypes, d.sampleTypes)
+	}
+	for i, typ := range expectedTypes {
+		if d.sampleTypes[i] != typ {
+			t.Errorf("wrong sample type %d. want=%q, got=%q", i, typ, d.sampleTypes[i])
+		}
+	}
+
+	expectedStacks := []string{"main", "f < main", "g < f < main", "g < main"}
+	expectedCalls := []int64{1, 3, 3, 1}
+	if len(d.stacks) != len(expectedStacks) {
+		t.Fatalf("wrong samples. want=%v, got=%v", expectedStacks, d.stacks)
+	}
+	for i, stack := range expectedStacks {
+		if d.stacks[i] != stack {
+			t.Errorf("wrong stack of sample %d. want=%q, got=%q", i, stack, d.stacks[i])
+		}
+		if d.values[i][0] != expectedCalls[i] {
+			t.Errorf("wrong calls of %s. want=%d, got=%d", stack, expectedCalls[i], d.values[i][0])
+		}
+	}
+	if gTime, fTime := d.values[2][1], d.values[1][1]; gTime < int64(3*time.Millisecond) || fTime >= gTime {
+		t.Errorf("time of g is not its own. f=%d, g=%d", fTime, gTime)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	p := New(time.Millisecond)
+	p.Start()
+	p.Enter(Function{Name: "busy", Line: 1}, 3)
+	time.Sleep(20 * time.Millisecond)
+	p.Exit()
+	p.Stop()
+
+	d := decode(t, p)
+	if d.sampleTypes[0] != "samples/count" {
+		t.Errorf("wrong first sample type. want=%q, got=%q", "samples/count", d.sampleTypes[0])
+	}
+	for i, stack := range d.stacks {
+		if stack == "busy < main" {
+			if d.values[i][0] == 0 || d.values[i][1] == 0 {
+				t.Errorf("busy has no samples: %v", d.values[i])
+			}
+			return
+		}
+	}
+	t.Errorf("no sample of busy in %v", d.stacks)
+}
@@ -0,0 +1,69 @@
+// profiler/proto.go
+
+package profiler
+
+// encoder writes the protocol buffer wire format, as much of it as the
+// pprof profile format uses.
+type encoder struct {
+	data []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (e *encoder) varint(x uint64) {
+	for x >= 0x80 {
+		e.data = append(e.data, byte(x)|0x80)
+		x >>= 7
+	}
+	e.data = append(e.data, byte(x))
+}
+
+func (e *encoder) key(field, wire int) {
+	e.varint(uint64(field)<<3 | uint64(wire))
+}
+
+// int64 writes a varint field, leaving it out if it is zero, as proto3
+// does.
+func (e *encoder) int64(field int, x int64) {
+	if x == 0 {
+		return
+	}
+	e.key(field, wireVarint)
+	e.varint(uint64(x))
+}
+
+func (e *encoder) uint64(field int, x uint64) {
+	e.int64(field, int64(x))
+}
+
+func (e *encoder) string(field int, s string) {
+	e.key(field, wireBytes)
+	e.varint(uint64(len(s)))
+	e.data = append(e.data, s...)
+}
+
+// packed writes a packed repeated varint field.
+func (e *encoder) packed(field int, xs []int64) {
+	if len(xs) == 0 {
+		return
+	}
+	var inner encoder
+	for _, x := range xs {
+		inner.varint(uint64(x))
+	}
+	e.key(field, wireBytes)
+	e.varint(uint64(len(inner.data)))
+	e.data = append(e.data, inner.data...)
+}
+
+// message writes the embedded message that fn writes.
+func (e *encoder) message(field int, fn func(*encoder)) {
+	var inner encoder
+	fn(&inner)
+	e.key(field, wireBytes)
+	e.varint(uint64(len(inner.data)))
+	e.data = append(e.data, inner.data...)
+}
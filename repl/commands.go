@@ -0,0 +1,173 @@
+//repl/commands.go
+
+package repl
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/token"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// command is a REPL command, invoked as ":name args".
+type command struct {
+	usage string
+	help  string
+	run   func(s *session, args string)
+
+	// local is set for commands that read files, which remote sessions
+	// cannot use.
+	local bool
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help":   {":help", "list the available commands", (*session).help, false},
+		"tokens": {":tokens <src>", "print the tokens of src", (*session).tokens, false},
+		"ast":    {":ast <src>", "print the syntax tree of src", (*session).ast, false},
+		"env":    {":env", "list the bindings and macros of the session", (*session).env, false},
+		"load":   {":load <file>", "evaluate a file in the session", (*session).load, true},
+		"reset":  {":reset", "discard all bindings and macros", (*session).resetCommand, false},
+		"time":   {":time <expr>", "evaluate expr and report the time and steps taken", (*session).time, false},
+		"type":   {":type <expr>", "evaluate expr and print the type of the result", (*session).typeOf, false},
+	}
+}
+
+// isCommand reports whether input is a command rather than code.
+func isCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), ":")
+}
+
+// splitCommand splits a command input into its name and arguments.
+func splitCommand(input string) (string, string) {
+	input = strings.TrimPrefix(strings.TrimSpace(input), ":")
+	name, args, _ := strings.Cut(input, " ")
+	return name, strings.TrimSpace(args)
+}
+
+// commandSource returns the part of input that is Monkey code: all of it
+// for code and the arguments for a command.
+func commandSource(input string) string {
+	if !isCommand(input) {
+		return input
+	}
+	name, args := splitCommand(input)
+	if name == "load" {
+		return ""
+	}
+	return args
+}
+
+func (s *session) command(input string) {
+	name, args := splitCommand(input)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "\tunknown command :%s, see :help\n", name)
+		return
+	}
+	if cmd.local && s.remote {
+		fmt.Fprintf(s.out, "\t:%s is not available in network sessions\n", name)
+		return
+	}
+	cmd.run(s, args)
+}
+
+func (s *session) help(args string) {
+	names := make([]string, 0, len(commands))
+	for name, cmd := range commands {
+		if !cmd.local || !s.remote {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%-16s %s\n", commands[name].usage, commands[name].help)
+	}
+}
+
+func (s *session) tokens(args string) {
+	l := lexer.New(args)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%d:%d\t%s\t%q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+}
+
+func (s *session) ast(args string) {
+	defer s.lock()()
+	if program := s.parse(args); program != nil {
+		ast.Dump(s.out, program)
+	}
+}
+
+func (s *session) env(args string) {
+	defer s.lock()()
+	bindings := s.interp.Env.Bindings()
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%s = %s\n", name, bindings[name].Repr())
+	}
+
+	macros := s.interp.MacroEnv.Bindings()
+	names = names[:0]
+	for name := range macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%s = %s\n", name, macros[name].Repr())
+	}
+}
+
+func (s *session) load(args string) {
+	if args == "" {
+		fmt.Fprintf(s.out, "\tusage: %s\n", commands["load"].usage)
+		return
+	}
+
+	content, err := os.ReadFile(args)
+	if err != nil {
+		fmt.Fprintf(s.out, "\t%s\n", err)
+		return
+	}
+
+	evaluated := s.run(string(content))
+	if evaluated != nil {
+		s.print(evaluated)
+	}
+}
+
+func (s *session) resetCommand(args string) {
+	s.reset()
+}
+
+func (s *session) time(args string) {
+	start := time.Now()
+	startSteps := s.interp.Env.Steps()
+
+	evaluated := s.run(args)
+
+	elapsed := time.Since(start)
+	steps := s.interp.Env.Steps() - startSteps
+	if evaluated != nil {
+		s.print(evaluated)
+	}
+	fmt.Fprintf(s.out, "time: %s, steps: %d\n", elapsed, steps)
+}
+
+func (s *session) typeOf(args string) {
+	evaluated := s.run(args)
+	if evaluated != nil {
+		fmt.Fprintf(s.out, "%s\n", evaluated.Type())
+	}
+}
@@ -0,0 +1,87 @@
+// repl/commands_test.go
+
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		inputs   []string
+		expected string
+	}{
+		{
+			[]string{":tokens let x = 1;"},
+			"1:1\tLET\t\"let\"\n1:5\tIDENT\t\"x\"\n1:7\t=\t\"=\"\n1:9\tINT\t\"1\"\n1:10\t;\t\";\"\n",
+		},
+		{
+			[]string{":ast -a"},
+			"Program\n  ExpressionStatement\n    PrefixExpression -\n      Identifier a\n",
+		},
+		{
+			[]string{"let b = 2;", "let a = 1;", "let m = macro() { quote(1) };", ":env"},
+			"a = 1\nb = 2\nm = macro() {\nquote(1)\n}\n",
+		},
+		{
+			[]string{"let a = 1;", ":reset", "a", ":env"},
+			"\t1:1: identifier not found: a\n",
+		},
+		{
+			[]string{":type [1, 2]", ":type fn(x) { x }"},
+			"ARRAY\nFUNCTION\n",
+		},
+		{
+			[]string{":nope"},
+			"\tunknown command :nope, see :help\n",
+		},
+		{
+			[]string{":ast let = 1;"},
+			"\texpected next token to be IDENT, got = instead\n\tno prefix parse function for = found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		s := newSession(&out, options{})
+		for _, input := range tt.inputs {
+			s.eval(input)
+		}
+
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. want=%q, got=%q", tt.inputs, tt.expected, out.String())
+		}
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.mk")
+	err := os.WriteFile(path, []byte("let double = fn(x) { x * 2 };\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s := newSession(&out, options{})
+	s.eval(":load " + path)
+	s.eval("double(21)")
+
+	if out.String() != "42\n" {
+		t.Errorf("wrong output. want=%q, got=%q", "42\n", out.String())
+	}
+}
+
+func TestTimeCommand(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, options{})
+	s.eval(":time 1 + 2")
+
+	expected := regexp.MustCompile(`^3\ntime: \S+, steps: 5\n$`)
+	if !expected.MatchString(out.String()) {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
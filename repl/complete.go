@@ -0,0 +1,69 @@
+//repl/complete.go
+
+package repl
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// completer returns the completions for the word ending at pos in line,
+// along with the position that word starts at.
+type completer func(line []rune, pos int) (start int, candidates []string)
+
+// complete completes identifiers from the keywords, the builtins and the
+// bindings and macros of the session, and command names after a colon.
+func (s *session) complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && isIdentRune(line[start-1]) {
+		start--
+	}
+	prefix := string(line[start:pos])
+
+	var names []string
+	if start == 1 && line[0] == ':' {
+		for name := range commands {
+			names = append(names, name)
+		}
+	} else {
+		if prefix == "" {
+			return start, nil
+		}
+		return start, s.interp.Complete(prefix)
+	}
+
+	return start, matching(names, prefix)
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// matching returns the sorted, distinct names that start with prefix.
+func matching(names []string, prefix string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// commonPrefix returns the longest prefix shared by all of words.
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
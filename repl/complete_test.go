@@ -0,0 +1,82 @@
+// repl/complete_test.go
+
+package repl
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSessionComplete(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, options{})
+	s.eval(`let result = 1; let rest_of = 2; let mymacro = macro() { quote(1) };`)
+	s.eval(`let f = fn() { let inner = 1; inner };`)
+
+	tests := []struct {
+		line          string
+		expectedStart int
+		expected      []string
+	}{
+		{"re", 0, []string{"rest", "rest_of", "result", "return"}},
+		{"1 + resu", 4, []string{"result"}},
+		{"pu", 0, []string{"push", "puts"}},
+		{"macroe", 0, []string{"macroexpand", "macroexpand_1"}},
+		{"my", 0, []string{"mymacro"}},
+		{"inn", 0, nil},
+		{"1 + ", 4, nil},
+		{":ty", 1, []string{"type"}},
+		{":", 1, []string{"ast", "env", "help", "load", "reset", "time", "tokens", "type"}},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		start, candidates := s.complete(line, len(line))
+		if start != tt.expectedStart {
+			t.Errorf("complete(%q) wrong start. want=%d, got=%d", tt.line, tt.expectedStart, start)
+		}
+		if !reflect.DeepEqual(candidates, tt.expected) {
+			t.Errorf("complete(%q) wrong candidates. want=%q, got=%q", tt.line, tt.expected, candidates)
+		}
+	}
+}
+
+func TestLineEditorTab(t *testing.T) {
+	s := newSession(io.Discard, options{})
+	s.eval(`let counter = 1; let count_all = 2;`)
+
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"ret\t\r", "return "},
+		{"cou\t\r", "count"},
+		{"cou\te\t\r", "counter "},
+		{"1 + coun\tt\r", "1 + countt"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		editor := newLineEditor(strings.NewReader(tt.keys), &out, loadHistory(""))
+		editor.complete = s.complete
+
+		line, err := editor.ReadLine(PROMPT)
+		if err != nil {
+			t.Fatalf("ReadLine(%q) failed: %s", tt.keys, err)
+		}
+		if line != tt.expected {
+			t.Errorf("ReadLine(%q) wrong. want=%q, got=%q", tt.keys, tt.expected, line)
+		}
+	}
+
+	var out bytes.Buffer
+	editor := newLineEditor(strings.NewReader("cou\t\t\r"), &out, loadHistory(""))
+	editor.complete = s.complete
+	editor.ReadLine(PROMPT)
+	if !strings.Contains(out.String(), "\r\ncount_all  counter\r\n") {
+		t.Errorf("second Tab did not list the completions. got=%q", out.String())
+	}
+}
@@ -0,0 +1,87 @@
+//repl/highlight.go
+
+package repl
+
+import (
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/token"
+	"os"
+	"strings"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorKeyword = "\x1b[1;35m"
+	colorLiteral = "\x1b[36m"
+	colorString  = "\x1b[32m"
+	colorBuiltin = "\x1b[34m"
+	colorIllegal = "\x1b[31m"
+)
+
+var builtinNames = func() map[string]bool {
+	names := map[string]bool{}
+	for _, name := range evaluator.BuiltinNames() {
+		names[name] = true
+	}
+	return names
+}()
+
+// highlight returns line with ANSI colors added to its tokens. Everything
+// between tokens, including whitespace, is copied unchanged.
+func highlight(line string) string {
+	var out strings.Builder
+	lineStarts := []int{0}
+	for i, ch := range line {
+		if ch == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	l := lexer.New(line)
+	last := 0
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		start := lineStarts[tok.Line-1] + tok.Column - 1
+		end := l.Offset()
+		out.WriteString(line[last:start])
+		if color := tokenColor(tok); color != "" {
+			out.WriteString(color + line[start:end] + colorReset)
+		} else {
+			out.WriteString(line[start:end])
+		}
+		last = end
+	}
+	out.WriteString(line[last:])
+	return out.String()
+}
+
+func tokenColor(tok token.Token) string {
+	switch tok.Type {
+	case token.LET, token.FUNCTION, token.IF, token.ELSE, token.RETURN, token.MACRO:
+		return colorKeyword
+	case token.INT, token.TRUE, token.FALSE, token.NULL:
+		return colorLiteral
+	case token.STRING:
+		return colorString
+	case token.IDENT:
+		if builtinNames[tok.Literal] {
+			return colorBuiltin
+		}
+	case token.ILLEGAL:
+		return colorIllegal
+	}
+	return ""
+}
+
+// colorEnabled reports whether the environment allows colored output on a
+// terminal, following the NO_COLOR convention.
+func colorEnabled() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return os.Getenv("TERM") != "dumb"
+}
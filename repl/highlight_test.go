@@ -0,0 +1,35 @@
+// repl/highlight_test.go
+
+package repl
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"x + y", "x + y"},
+		{
+			`let s = "a b";`,
+			colorKeyword + "let" + colorReset + " s = " + colorString + `"a b"` + colorReset + ";",
+		},
+		{
+			"len([1, true])",
+			colorBuiltin + "len" + colorReset + "([" + colorLiteral + "1" + colorReset + ", " +
+				colorLiteral + "true" + colorReset + "])",
+		},
+		{
+			"if (x) {\n  @\n}",
+			colorKeyword + "if" + colorReset + " (x) {\n  " + colorIllegal + "@" + colorReset + "\n}",
+		},
+		{`"open`, colorString + `"open` + colorReset},
+	}
+
+	for _, tt := range tests {
+		if got := highlight(tt.input); got != tt.expected {
+			t.Errorf("highlight(%q) wrong.\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+//repl/history.go
+
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const HISTORY_FILE = ".monkey_history"
+
+// maxHistory is the number of entries kept in memory and on disk.
+const maxHistory = 1000
+
+// history is the list of previous inputs, optionally persisted to a file
+// with one entry per line. Entries may span lines; in the file, their
+// newlines are written as \n and their backslashes doubled.
+type history struct {
+	entries []string
+	path    string
+}
+
+// defaultHistoryPath returns the history file in the user's home directory,
+// or "" if there is none.
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+// loadHistory reads the history stored at path. A missing or unreadable file
+// gives an empty history; an empty path gives one that is never saved.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, unescapeEntry(line))
+		}
+	}
+	h.trim()
+	return h
+}
+
+// Add appends entry to the history and the history file. Blank entries and
+// repeats of the last entry are dropped.
+func (h *history) Add(entry string) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if h.trim() {
+		h.save()
+		return
+	}
+	h.append(entry)
+}
+
+func (h *history) Len() int {
+	return len(h.entries)
+}
+
+func (h *history) At(i int) string {
+	return h.entries[i]
+}
+
+// trim drops the oldest entries beyond maxHistory and reports whether any
+// were dropped.
+func (h *history) trim() bool {
+	if len(h.entries) <= maxHistory {
+		return false
+	}
+	h.entries = h.entries[len(h.entries)-maxHistory:]
+	return true
+}
+
+func (h *history) append(entry string) {
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(escapeEntry(entry) + "\n")
+}
+
+func (h *history) save() {
+	if h.path == "" {
+		return
+	}
+	var content strings.Builder
+	for _, entry := range h.entries {
+		content.WriteString(escapeEntry(entry) + "\n")
+	}
+	os.WriteFile(h.path, []byte(content.String()), 0600)
+}
+
+var entryEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// escapeEntry returns entry as one line of the history file.
+func escapeEntry(entry string) string {
+	return entryEscaper.Replace(entry)
+}
+
+// unescapeEntry returns the entry written as line by escapeEntry. Other
+// backslashes, as in files written before entries were escaped, are kept.
+func unescapeEntry(line string) string {
+	var out strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			switch line[i+1] {
+			case 'n':
+				out.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				i++
+			}
+		}
+		out.WriteByte(line[i])
+	}
+	return out.String()
+}
@@ -0,0 +1,81 @@
+// repl/history_test.go
+
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+
+	h := loadHistory(path)
+	h.Add("let x = 1;")
+	h.Add("let x = 1;")
+	h.Add("  ")
+	h.Add("let f = fn(x) {\n  x\n};")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("history file not written: %s", err)
+	}
+	expected := "let x = 1;\nlet f = fn(x) {\\n  x\\n};\n"
+	if string(content) != expected {
+		t.Errorf("wrong history file. want=%q, got=%q", expected, content)
+	}
+
+	reloaded := loadHistory(path)
+	if reloaded.Len() != 2 || reloaded.At(1) != "let f = fn(x) {\n  x\n};" {
+		t.Errorf("wrong reloaded history. got=%q", reloaded.entries)
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+	entries := []string{
+		"let f = fn(x) { // the argument\n  x + 1 // one more\n};",
+		`puts("a\nb", "back\\slash\\n")`,
+	}
+
+	h := loadHistory(path)
+	for _, entry := range entries {
+		h.Add(entry)
+	}
+
+	reloaded := loadHistory(path)
+	if !reflect.DeepEqual(reloaded.entries, entries) {
+		t.Fatalf("wrong reloaded history.\nwant=%q\ngot= %q", entries, reloaded.entries)
+	}
+	if isIncomplete(reloaded.At(0)) {
+		t.Errorf("reloaded entry is incomplete: %q", reloaded.At(0))
+	}
+
+	var out bytes.Buffer
+	s := newSession(&out, options{})
+	s.eval(reloaded.At(0))
+	s.eval("f(1)")
+	if out.String() != "2\n" {
+		t.Errorf("wrong output of the reloaded entry. got=%q", out.String())
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+
+	h := loadHistory(path)
+	for i := 0; i <= maxHistory; i++ {
+		h.Add(string(rune('a'+i%26)) + string(rune('a'+i/26%26)))
+	}
+
+	reloaded := loadHistory(path)
+	if reloaded.Len() != maxHistory {
+		t.Fatalf("wrong history length. want=%d, got=%d", maxHistory, reloaded.Len())
+	}
+	if reloaded.At(0) != "ba" {
+		t.Errorf("oldest entry not dropped. got=%q", reloaded.At(0))
+	}
+}
@@ -0,0 +1,55 @@
+//repl/incomplete.go
+
+package repl
+
+import (
+	"monkey/lexer"
+	"monkey/token"
+)
+
+// danglingTokens are the tokens that cannot end a complete statement, so an
+// input ending in one of them is continued on the next line.
+var danglingTokens = map[token.TokenType]bool{
+	token.ASSIGN:   true,
+	token.PLUS:     true,
+	token.MINUS:    true,
+	token.SLASH:    true,
+	token.ASTERISK: true,
+	token.LT:       true,
+	token.GT:       true,
+	token.BANG:     true,
+	token.EQ:       true,
+	token.NOT_EQ:   true,
+	token.COMMA:    true,
+	token.COLON:    true,
+	token.FUNCTION: true,
+	token.MACRO:    true,
+	token.LET:      true,
+	token.IF:       true,
+	token.ELSE:     true,
+	token.RETURN:   true,
+}
+
+// isIncomplete reports whether input needs more lines before it can be
+// parsed: it has unbalanced braces, brackets or parentheses, an unterminated
+// string, or ends in an operator.
+func isIncomplete(input string) bool {
+	depth := 0
+	last := token.Token{Type: token.EOF}
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.STRING:
+			if l.Unterminated() {
+				return true
+			}
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		}
+		last = tok
+	}
+
+	return depth > 0 || danglingTokens[last.Type]
+}
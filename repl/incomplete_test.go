@@ -0,0 +1,39 @@
+// repl/incomplete_test.go
+
+package repl
+
+import "testing"
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"", false},
+		{"let x = 5;", false},
+		{"let x =", true},
+		{"1 +", true},
+		{"1 + 2", false},
+		{"let add = fn(x, y) {", true},
+		{"let add = fn(x, y) {\n  x + y;\n}", false},
+		{"[1, 2,", true},
+		{"[1, 2, 3]", false},
+		{`{"a": 1,`, true},
+		{`{"a":`, true},
+		{"puts(1", true},
+		{"if (x) { 1 } else", true},
+		{`"unterminated`, true},
+		{`"{"`, false},
+		{`"say \"hi`, true},
+		{`"say \"hi\""`, false},
+		{`let x = 1; // say "hi`, false},
+		{`let x = "a // b`, true},
+		{"1 }", false},
+	}
+
+	for _, tt := range tests {
+		if got := isIncomplete(tt.input); got != tt.expected {
+			t.Errorf("isIncomplete(%q) wrong. want=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+//repl/lineedit.go
+
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// Control keys understood by the line editor.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyNewline   = 10
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlT     = 20
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyCtrlY     = 25
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor reads lines from a terminal in raw mode, providing Emacs-style
+// editing and history. It only deals with bytes; putting the terminal into
+// raw mode is up to the caller.
+type lineEditor struct {
+	in        *bufio.Reader
+	out       io.Writer
+	history   *history
+	complete  completer
+	highlight func(string) string // colors the line when redrawing it, if set
+
+	prompt  string
+	buf     []rune
+	pos     int
+	killed  []rune
+	histPos int    // index into history while browsing, Len() when not
+	draft   []rune // the line being edited before browsing history
+	tabbed  bool   // the previous key was a Tab that completed nothing
+}
+
+func newLineEditor(in io.Reader, out io.Writer, h *history) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, history: h}
+}
+
+// ReadLine shows prompt and returns the line the user enters. It returns
+// io.EOF on Ctrl-D at an empty line and errInterrupted on Ctrl-C.
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	e.prompt = prompt
+	e.buf = e.buf[:0]
+	e.pos = 0
+	e.histPos = e.history.Len()
+	e.draft = nil
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		if r == keyTab {
+			e.completeWord()
+			e.refresh()
+			continue
+		}
+		e.tabbed = false
+
+		switch r {
+		case keyEnter, keyNewline:
+			io.WriteString(e.out, "\r\n")
+			return string(e.buf), nil
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteForward()
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.buf)
+		case keyCtrlB:
+			e.moveLeft()
+		case keyCtrlF:
+			e.moveRight()
+		case keyBackspace, keyDelete:
+			e.deleteBackward()
+		case keyCtrlK:
+			e.kill(e.pos, len(e.buf))
+		case keyCtrlU:
+			e.kill(0, e.pos)
+		case keyCtrlW:
+			e.kill(e.wordStart(), e.pos)
+		case keyCtrlY:
+			e.insert(e.killed...)
+		case keyCtrlT:
+			e.transpose()
+		case keyCtrlP:
+			e.historyPrev()
+		case keyCtrlN:
+			e.historyNext()
+		case keyCtrlL:
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case keyEscape:
+			if err := e.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert(r)
+			}
+		}
+		e.refresh()
+	}
+}
+
+// escape handles the key sequences starting with ESC: arrows, Home, End and
+// Delete as sent by common terminals, and Alt-b, Alt-f and Alt-d.
+func (e *lineEditor) escape() error {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+
+	switch r {
+	case 'b':
+		e.pos = e.wordStart()
+		return nil
+	case 'f':
+		e.pos = e.wordEnd()
+		return nil
+	case 'd':
+		e.kill(e.pos, e.wordEnd())
+		return nil
+	case '[', 'O':
+	default:
+		return nil
+	}
+
+	var param []rune
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r < '0' || r > '9' {
+			break
+		}
+		param = append(param, r)
+	}
+
+	switch r {
+	case 'A':
+		e.historyPrev()
+	case 'B':
+		e.historyNext()
+	case 'C':
+		e.moveRight()
+	case 'D':
+		e.moveLeft()
+	case 'H':
+		e.pos = 0
+	case 'F':
+		e.pos = len(e.buf)
+	case '~':
+		switch string(param) {
+		case "1", "7":
+			e.pos = 0
+		case "4", "8":
+			e.pos = len(e.buf)
+		case "3":
+			e.deleteForward()
+		}
+	}
+	return nil
+}
+
+// completeWord completes the word before the cursor as far as all its
+// completions agree. A further Tab while it is still ambiguous lists the
+// completions.
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	start, candidates := e.complete(e.buf, e.pos)
+	if len(candidates) == 0 {
+		return
+	}
+
+	typed := e.pos - start
+	prefix := []rune(commonPrefix(candidates))
+	if len(prefix) > typed {
+		e.insert(prefix[typed:]...)
+		if len(candidates) == 1 {
+			e.insert(' ')
+		}
+		e.tabbed = len(candidates) > 1
+		return
+	}
+
+	if e.tabbed {
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+	e.tabbed = true
+}
+
+// refresh redraws the prompt and line and places the cursor. The newlines
+// of an entry recalled from the history are shown as ↵, so that it stays on
+// one line.
+func (e *lineEditor) refresh() {
+	line := string(e.buf)
+	if e.highlight != nil {
+		line = e.highlight(line)
+	}
+	line = strings.ReplaceAll(line, "\n", "↵")
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, line)
+	if back := len(e.buf) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (e *lineEditor) insert(runes ...rune) {
+	tail := append([]rune{}, e.buf[e.pos:]...)
+	e.buf = append(append(e.buf[:e.pos], runes...), tail...)
+	e.pos += len(runes)
+}
+
+func (e *lineEditor) moveLeft() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+func (e *lineEditor) moveRight() {
+	if e.pos < len(e.buf) {
+		e.pos++
+	}
+}
+
+func (e *lineEditor) deleteBackward() {
+	if e.pos > 0 {
+		e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+		e.pos--
+	}
+}
+
+func (e *lineEditor) deleteForward() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+	}
+}
+
+// kill removes buf[from:to] and keeps it for a later yank.
+func (e *lineEditor) kill(from, to int) {
+	if from >= to {
+		return
+	}
+	e.killed = append([]rune{}, e.buf[from:to]...)
+	e.buf = append(e.buf[:from], e.buf[to:]...)
+	e.pos = from
+}
+
+func (e *lineEditor) transpose() {
+	if e.pos == 0 || len(e.buf) < 2 {
+		return
+	}
+	if e.pos == len(e.buf) {
+		e.pos--
+	}
+	e.buf[e.pos-1], e.buf[e.pos] = e.buf[e.pos], e.buf[e.pos-1]
+	e.pos++
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordStart returns the start of the word before the cursor.
+func (e *lineEditor) wordStart() int {
+	i := e.pos
+	for i > 0 && !isWordRune(e.buf[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(e.buf[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the end of the word after the cursor.
+func (e *lineEditor) wordEnd() int {
+	i := e.pos
+	for i < len(e.buf) && !isWordRune(e.buf[i]) {
+		i++
+	}
+	for i < len(e.buf) && isWordRune(e.buf[i]) {
+		i++
+	}
+	return i
+}
+
+func (e *lineEditor) historyPrev() {
+	if e.histPos == 0 {
+		return
+	}
+	if e.histPos == e.history.Len() {
+		e.draft = append([]rune{}, e.buf...)
+	}
+	e.histPos--
+	e.setLine([]rune(e.history.At(e.histPos)))
+}
+
+func (e *lineEditor) historyNext() {
+	if e.histPos >= e.history.Len() {
+		return
+	}
+	e.histPos++
+	if e.histPos == e.history.Len() {
+		e.setLine(e.draft)
+		return
+	}
+	e.setLine([]rune(e.history.At(e.histPos)))
+}
+
+func (e *lineEditor) setLine(line []rune) {
+	e.buf = append(e.buf[:0], line...)
+	e.pos = len(e.buf)
+}
@@ -0,0 +1,103 @@
+// repl/lineedit_test.go
+
+package repl
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"let x = 5;\r", "let x = 5;"},
+		{"ac\x02b\r", "abc"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"abc\x7f\x7fd\r", "ad"},
+		{"abc\x1b[D\x1b[D\x1b[3~\r", "ac"},
+		{"abc def\x17\r", "abc "},
+		{"abc def\x01\x0b\x19\x19\r", "abc defabc def"},
+		{"abc def\x15x\r", "x"},
+		{"foo bar\x1bb\x1bbX\r", "Xfoo bar"},
+		{"ab\x14\r", "ba"},
+		{"x\x1b[Hy\x1b[Fz\r", "yxz"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		editor := newLineEditor(strings.NewReader(tt.keys), &out, loadHistory(""))
+
+		line, err := editor.ReadLine(PROMPT)
+		if err != nil {
+			t.Fatalf("ReadLine(%q) failed: %s", tt.keys, err)
+		}
+		if line != tt.expected {
+			t.Errorf("ReadLine(%q) wrong. want=%q, got=%q", tt.keys, tt.expected, line)
+		}
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	h := loadHistory("")
+	h.Add("first")
+	h.Add("second")
+
+	keys := "draft\x1b[A\x1b[A\r" + "\x10\x10\x0e\r" + "draft\x10\x0e\r"
+	editor := newLineEditor(strings.NewReader(keys), io.Discard, h)
+
+	for _, expected := range []string{"first", "second", "draft"} {
+		line, err := editor.ReadLine(PROMPT)
+		if err != nil {
+			t.Fatalf("ReadLine failed: %s", err)
+		}
+		if line != expected {
+			t.Errorf("ReadLine wrong. want=%q, got=%q", expected, line)
+		}
+	}
+}
+
+func TestLineEditorMultiLineHistory(t *testing.T) {
+	h := loadHistory("")
+	h.Add("let f = fn() { // one\n  1\n};")
+
+	var out bytes.Buffer
+	editor := newLineEditor(strings.NewReader("\x1b[A\r"), &out, h)
+	line, err := editor.ReadLine(PROMPT)
+	if err != nil {
+		t.Fatalf("ReadLine failed: %s", err)
+	}
+	if line != "let f = fn() { // one\n  1\n};" {
+		t.Errorf("ReadLine wrong. got=%q", line)
+	}
+	if !strings.Contains(out.String(), PROMPT+"let f = fn() { // one↵  1↵};") {
+		t.Errorf("entry not shown on one line. got=%q", out.String())
+	}
+}
+
+func TestLineEditorControl(t *testing.T) {
+	editor := newLineEditor(strings.NewReader("abc\x03\x04"), io.Discard, loadHistory(""))
+
+	if _, err := editor.ReadLine(PROMPT); err != errInterrupted {
+		t.Errorf("expected Ctrl-C to interrupt. got=%v", err)
+	}
+	if _, err := editor.ReadLine(PROMPT); err != io.EOF {
+		t.Errorf("expected Ctrl-D on an empty line to end input. got=%v", err)
+	}
+}
+func TestLineEditorHighlight(t *testing.T) {
+	var out bytes.Buffer
+	editor := newLineEditor(strings.NewReader("let\r"), &out, loadHistory(""))
+	editor.highlight = highlight
+
+	line, err := editor.ReadLine(PROMPT)
+	if err != nil || line != "let" {
+		t.Fatalf("ReadLine() = %q, %v", line, err)
+	}
+	if !strings.Contains(out.String(), PROMPT+colorKeyword+"let"+colorReset) {
+		t.Errorf("line not highlighted: %q", out.String())
+	}
+}
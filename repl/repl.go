@@ -6,13 +6,16 @@ import (
 	"bufio" // for reading input
 	"fmt"
 	"io"
-	"monkey/evaluator"
-	"monkey/lexer"
-	"monkey/object"
-	"monkey/parser"
+	"monkey/interpreter"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
 )
 
 const PROMPT = ">> "
+const CONTINUATION_PROMPT = ".. "
+const EXIT_HINT = "(To exit, press Ctrl-C again or Ctrl-D)"
 const WELCOME_ASCII = `
  ##   ##   #####   ##   ##   ##  ###  ####### ##  ##
  ### ###  ##   ##  ###  ##   ##  ##   ##      ##  ##
@@ -25,39 +28,197 @@ const WELCOME_ASCII = `
 
 `
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	environment := object.NewEnvironment()
-	macroEnv := object.NewEnvironment()
-	fmt.Fprintf(out, WELCOME_ASCII)
+// Option configures a REPL session started by Start.
+type Option func(*options)
+
+type options struct {
+	macroTrace  io.Writer
+	historyPath *string
+	color       *bool
+
+	// Used by Serve only.
+	token       string
+	idleTimeout time.Duration
+	shared      *interpreter.Interpreter
+	remote      bool
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMacroTrace makes the REPL log every macro expansion step to w.
+func WithMacroTrace(w io.Writer) Option {
+	return func(o *options) {
+		o.macroTrace = w
+	}
+}
+
+// WithHistoryFile sets the file the history of an interactive session is
+// kept in. An empty path keeps the history in memory only. The default is
+// HISTORY_FILE in the user's home directory.
+func WithHistoryFile(path string) Option {
+	return func(o *options) {
+		o.historyPath = &path
+	}
+}
+
+// WithColor turns syntax highlighting of input on or off. By default input
+// is highlighted when the REPL runs on a terminal and NO_COLOR is not set.
+func WithColor(enabled bool) Option {
+	return func(o *options) {
+		o.color = &enabled
+	}
+}
+
+// lineReader reads the lines of input typed at the REPL.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// scannerReader reads lines from input that is not a terminal.
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// terminalReader reads lines with a lineEditor, switching the terminal into
+// raw mode only while a line is being edited.
+type terminalReader struct {
+	fd     int
+	editor *lineEditor
+}
+
+func (r *terminalReader) ReadLine(prompt string) (string, error) {
+	state, err := makeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restoreTerminal(r.fd, state)
+	return r.editor.ReadLine(prompt)
+}
+
+func newLineReader(in io.Reader, out io.Writer, o options, complete completer) (lineReader, *history) {
+	inFile, inOK := in.(*os.File)
+	outFile, outOK := out.(*os.File)
+	if !inOK || !outOK || !isTerminal(int(inFile.Fd())) || !isTerminal(int(outFile.Fd())) {
+		return &scannerReader{scanner: bufio.NewScanner(in), out: out}, nil
+	}
+
+	path := defaultHistoryPath()
+	if o.historyPath != nil {
+		path = *o.historyPath
+	}
+	h := loadHistory(path)
+	editor := newLineEditor(in, out, h)
+	editor.complete = complete
+	color := colorEnabled()
+	if o.color != nil {
+		color = *o.color
+	}
+	if color {
+		editor.highlight = highlight
+	}
+	return &terminalReader{fd: int(inFile.Fd()), editor: editor}, h
+}
+
+// readInput reads lines until they form a complete input, showing
+// CONTINUATION_PROMPT for every line after the first.
+func readInput(reader lineReader) (string, error) {
+	var lines []string
+	prompt := PROMPT
 	for {
-		fmt.Fprintf(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+		line, err := reader.ReadLine(prompt)
+		if err == io.EOF && len(lines) > 0 {
+			return strings.Join(lines, "\n"), nil
+		}
+		if err != nil {
+			return "", err
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+		if !isIncomplete(commandSource(input)) {
+			return input, nil
+		}
+		prompt = CONTINUATION_PROMPT
+	}
+}
 
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+func Start(in io.Reader, out io.Writer, opts ...Option) {
+	o := newOptions(opts)
+	s := newSession(out, o)
+	reader, hist := newLineReader(in, out, o, s.complete)
+	if _, ok := reader.(*terminalReader); ok {
+		s.width = terminalWidth(int(out.(*os.File).Fd()))
+		stop := handleInterrupts(s)
+		defer stop()
+	}
+
+	loop(s, reader, hist)
+}
+
+// loop reads and evaluates input in s until the input ends or the user
+// exits. hist, if not nil, records every input.
+func loop(s *session, reader lineReader, hist *history) {
+	out := s.out
+	fmt.Fprintf(out, WELCOME_ASCII)
+	interrupted := false
+	for {
+		input, err := readInput(reader)
+		if err == errInterrupted {
+			if interrupted {
+				return
+			}
+			interrupted = true
+			io.WriteString(out, EXIT_HINT+"\n")
 			continue
 		}
-		evaluator.DefineMacros(program, macroEnv)
-		expanded := evaluator.ExpandMacros(program, macroEnv)
-
-		evaluated := evaluator.Eval(expanded, environment)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect()+"\n")
+		if err != nil {
+			return
+		}
+		if hist != nil {
+			hist.Add(input)
 		}
+
+		s.eval(input)
+		interrupted = s.interrupted.Load()
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// handleInterrupts makes SIGINT stop the evaluation running in s instead of
+// the process. A second SIGINT while the evaluation is still running exits.
+// The returned function restores the default handling.
+func handleInterrupts(s *session) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	go func() {
+		for range signals {
+			if s.interrupted.Load() && s.evaluating.Load() {
+				os.Exit(130)
+			}
+			s.interrupt()
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(signals)
 	}
 }
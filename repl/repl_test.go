@@ -0,0 +1,69 @@
+// repl/repl_test.go
+
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartMultiLineInput(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+add(1,
+  2)
+`
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	output := strings.TrimPrefix(out.String(), WELCOME_ASCII)
+	expected := PROMPT + CONTINUATION_PROMPT + CONTINUATION_PROMPT +
+		PROMPT + CONTINUATION_PROMPT + "3\n" + PROMPT
+	if output != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, output)
+	}
+}
+
+func TestSessionInterrupt(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, options{})
+	s.eval(`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) + f(n - 1) } };`)
+
+	if s.interrupt() {
+		t.Fatalf("interrupt reported an evaluation while idle")
+	}
+
+	go func() {
+		for !s.interrupt() {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	s.eval(`f(40)`)
+
+	if out.String() != "Error: interrupted\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	out.Reset()
+	s.eval(`f(2)`)
+	if out.String() != "0\n" {
+		t.Errorf("session not usable after an interrupt. got=%q", out.String())
+	}
+}
+
+func TestSessionPrettyPrint(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, options{})
+	s.width = 20
+
+	s.eval(`"hi"`)
+	s.eval(`{"list": ["first", "second"]}`)
+
+	expected := "\"hi\"\n{\n  \"list\": [\n    \"first\",\n    \"second\"\n  ]\n}\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+}
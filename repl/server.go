@@ -0,0 +1,113 @@
+//repl/server.go
+
+package repl
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/interpreter"
+	"net"
+	"sync"
+	"time"
+)
+
+const TOKEN_PROMPT = "token: "
+const AUTH_FAILED = "authentication failed"
+
+// WithToken makes Serve ask every connection for token before starting a
+// session, closing connections that send anything else.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithIdleTimeout makes Serve close connections that send nothing for d.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithSharedInterpreter makes every connection accepted by Serve evaluate
+// in interp instead of a session of its own, so that bindings made by one
+// are seen by all. Evaluations from different connections take turns.
+// Imports are as interp allows, so it should be made with
+// interpreter.WithStdlibOnly to keep clients from reading files.
+func WithSharedInterpreter(interp *interpreter.Interpreter) Option {
+	return func(o *options) {
+		o.shared = interp
+	}
+}
+
+// Serve accepts connections on l, running a REPL session on each until the
+// client disconnects. It returns nil once l is closed. Sessions cannot use
+// :load, and can import only the modules of the standard library, so that
+// clients cannot read the files of the server.
+func Serve(l net.Listener, opts ...Option) error {
+	o := newOptions(opts)
+	var mu *sync.Mutex
+	if o.shared != nil {
+		mu = &sync.Mutex{}
+	}
+
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go serveConn(conn, o, mu)
+	}
+}
+
+func serveConn(conn net.Conn, o options, mu *sync.Mutex) {
+	defer conn.Close()
+
+	// A panic ends this connection only, not the server.
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(conn, "\tinternal error: %v\n", r)
+		}
+	}()
+
+	var in io.Reader = conn
+	if o.idleTimeout > 0 {
+		in = &idleReader{conn: conn, timeout: o.idleTimeout}
+	}
+	reader := &scannerReader{scanner: bufio.NewScanner(in), out: conn}
+
+	if o.token != "" {
+		token, err := reader.ReadLine(TOKEN_PROMPT)
+		if err != nil {
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(o.token)) != 1 {
+			io.WriteString(conn, AUTH_FAILED+"\n")
+			return
+		}
+	}
+
+	o.remote = true
+	s := newSession(conn, o)
+	s.mu = mu
+	loop(s, reader, nil)
+}
+
+// idleReader reads from conn, failing reads that wait longer than timeout.
+type idleReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	if err := r.conn.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(p)
+}
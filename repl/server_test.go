@@ -0,0 +1,183 @@
+// repl/server_test.go
+
+package repl
+
+import (
+	"io"
+	"monkey/interpreter"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve starts Serve on a localhost listener for the duration of the test.
+func serve(t *testing.T, network, address string, opts ...Option) net.Addr {
+	t.Helper()
+	l, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- Serve(l, opts...) }()
+	t.Cleanup(func() {
+		l.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	})
+	return l.Addr()
+}
+
+// sendSession sends input to the server at addr and returns everything it
+// writes back before closing the connection.
+func sendSession(t *testing.T, addr net.Addr, input string) string {
+	t.Helper()
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, input)
+	conn.(interface{ CloseWrite() error }).CloseWrite()
+	output, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return strings.TrimPrefix(string(output), WELCOME_ASCII)
+}
+
+func TestServeSeparateSessions(t *testing.T) {
+	addr := serve(t, "tcp", "127.0.0.1:0")
+
+	output := sendSession(t, addr, "let x = 5;\nx * 2\n")
+	expected := PROMPT + PROMPT + "10\n" + PROMPT
+	if output != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, output)
+	}
+
+	output = sendSession(t, addr, "x\n")
+	expected = PROMPT + "\t1:1: identifier not found: x\n" + PROMPT
+	if output != expected {
+		t.Errorf("bindings leaked between sessions. want=%q, got=%q", expected, output)
+	}
+}
+
+func TestServeSharedInterpreter(t *testing.T) {
+	interp := interpreter.New()
+	addr := serve(t, "tcp", "127.0.0.1:0", WithSharedInterpreter(interp))
+
+	sendSession(t, addr, "let x = 5;\n")
+	output := sendSession(t, addr, "x\n")
+	expected := PROMPT + "5\n" + PROMPT
+	if output != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, output)
+	}
+
+	if _, ok := interp.Env.Get("x"); !ok {
+		t.Errorf("binding not made in the shared interpreter")
+	}
+}
+
+func TestServeToken(t *testing.T) {
+	addr := serve(t, "tcp", "127.0.0.1:0", WithToken("secret"))
+
+	output := sendSession(t, addr, "guess\n1 + 1\n")
+	expected := TOKEN_PROMPT + AUTH_FAILED + "\n"
+	if output != expected {
+		t.Errorf("wrong output for bad token. want=%q, got=%q", expected, output)
+	}
+
+	output = sendSession(t, addr, "secret\n1 + 1\n")
+	expected = TOKEN_PROMPT + WELCOME_ASCII + PROMPT + "2\n" + PROMPT
+	if output != expected {
+		t.Errorf("wrong output for good token. want=%q, got=%q", expected, output)
+	}
+}
+
+func TestServeIdleTimeout(t *testing.T) {
+	addr := serve(t, "tcp", "127.0.0.1:0", WithIdleTimeout(50*time.Millisecond))
+
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	output, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("idle connection not closed: %v", err)
+	}
+	if string(output) != WELCOME_ASCII+PROMPT {
+		t.Errorf("wrong output. got=%q", output)
+	}
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	addr := serve(t, "unix", filepath.Join(t.TempDir(), "monkey.sock"))
+
+	output := sendSession(t, addr, `len("four")`+"\n")
+	expected := PROMPT + "4\n" + PROMPT
+	if output != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, output)
+	}
+}
+
+// panicWriter panics on every write.
+type panicWriter struct{}
+
+func (panicWriter) Write(p []byte) (int, error) {
+	panic("write to panicWriter")
+}
+
+func TestServePanics(t *testing.T) {
+	// Tracing a macro expansion to a panicWriter stands in for a bug that
+	// panics the interpreter.
+	addr := serve(t, "tcp", "127.0.0.1:0", WithMacroTrace(panicWriter{}))
+
+	// The session goes on after an input that panics the interpreter, and
+	// other sessions are not affected.
+	output := sendSession(t, addr, "let m = macro() { quote(1) }; m()\n1 + 1\n")
+	if strings.Count(output, "internal error") != 1 || !strings.HasSuffix(output, PROMPT+"2\n"+PROMPT) {
+		t.Errorf("wrong output. got=%q", output)
+	}
+
+	output = sendSession(t, addr, "2 + 2\n")
+	expected := PROMPT + "4\n" + PROMPT
+	if output != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, output)
+	}
+}
+
+func TestServeLoad(t *testing.T) {
+	addr := serve(t, "tcp", "127.0.0.1:0")
+
+	output := sendSession(t, addr, ":load /etc/passwd\n:help\n")
+	if !strings.HasPrefix(output, PROMPT+"\t:load is not available in network sessions\n") {
+		t.Errorf("wrong output for :load. got=%q", output)
+	}
+	if strings.Contains(output, ":load <file>") {
+		t.Errorf(":help lists :load. got=%q", output)
+	}
+}
+
+func TestServeImports(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.mk")
+	if err := os.WriteFile(path, []byte(`export let secret = "hidden";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	addr := serve(t, "tcp", "127.0.0.1:0")
+
+	output := sendSession(t, addr, `import "`+path+`" as m; m["secret"]`+"\n"+`import "std/list" as list; list["reverse"]([1, 2])`+"\n")
+	if strings.Contains(output, "hidden") || !strings.Contains(output, "only modules of the standard library can be imported") {
+		t.Errorf("wrong output for a file import. got=%q", output)
+	}
+	if !strings.HasSuffix(output, PROMPT+"[2, 1]\n"+PROMPT) {
+		t.Errorf("wrong output for a standard library import. got=%q", output)
+	}
+}
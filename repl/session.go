@@ -0,0 +1,176 @@
+//repl/session.go
+
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/interpreter"
+	"monkey/object"
+	"sync"
+	"sync/atomic"
+)
+
+// session is the state of one REPL: the interpreter holding its bindings and
+// macros, and where output goes.
+type session struct {
+	out    io.Writer
+	interp *interpreter.Interpreter
+	width  int // columns values are pretty-printed to
+
+	// mu, if not nil, is held while evaluating so that sessions sharing
+	// interp take turns.
+	mu *sync.Mutex
+
+	// remote is set for sessions served over the network, which may not
+	// use the commands that read files, nor import modules from them.
+	remote bool
+
+	// evaluating is set while code typed at the prompt is running, and
+	// interrupted once that code has been interrupted.
+	evaluating  atomic.Bool
+	interrupted atomic.Bool
+}
+
+func newSession(out io.Writer, o options) *session {
+	if o.shared != nil {
+		return &session{out: out, interp: o.shared, width: 80, remote: o.remote}
+	}
+
+	var opts []interpreter.Option
+	if o.macroTrace != nil {
+		opts = append(opts, interpreter.WithMacroTrace(o.macroTrace))
+	}
+	if o.remote {
+		opts = append(opts, interpreter.WithStdlibOnly())
+	}
+	return &session{out: out, interp: interpreter.New(opts...), width: 80, remote: o.remote}
+}
+
+// reset discards every binding and macro defined so far.
+func (s *session) reset() {
+	defer s.lock()()
+	s.interp.Reset()
+}
+
+// lock takes mu, if the session has one, and returns the function that
+// releases it.
+func (s *session) lock() (unlock func()) {
+	if s.mu == nil {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// parse parses and macro-expands input, printing any errors. It returns nil
+// if there were errors. The caller must hold the session's lock.
+func (s *session) parse(input string) ast.Node {
+	program, err := s.interp.Parse(input)
+	if err != nil {
+		s.printError(err)
+		return nil
+	}
+	return program
+}
+
+// run evaluates input in the session. It returns nil if input could not be
+// parsed or resolved, which it reports, or evaluated to nothing; runtime
+// errors are returned as error objects.
+func (s *session) run(input string) object.Object {
+	defer s.lock()()
+	s.evaluating.Store(true)
+	defer s.evaluating.Store(false)
+
+	program := s.parse(input)
+	if program == nil {
+		return nil
+	}
+
+	evaluated, err := s.interp.Eval(program)
+	var runtimeErr *interpreter.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr.Err
+	}
+	if err != nil {
+		s.printError(err)
+		return nil
+	}
+	return evaluated
+}
+
+// interrupt handles a SIGINT: it stops the evaluation in progress, if any,
+// and reports whether there was one.
+func (s *session) interrupt() bool {
+	if !s.evaluating.Load() {
+		return false
+	}
+	s.interrupted.Store(true)
+	s.interp.Interrupt()
+	return true
+}
+
+// eval handles one complete input: a command if it starts with a colon and
+// code to evaluate otherwise. An input that makes the evaluator panic is
+// reported as an internal error, leaving the session running.
+func (s *session) eval(input string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(s.out, "\tinternal error: %v\n", r)
+		}
+	}()
+
+	s.interrupted.Store(false)
+	if isCommand(input) {
+		s.command(input)
+		return
+	}
+
+	evaluated := s.run(input)
+	if evaluated != nil {
+		s.print(evaluated)
+	}
+}
+
+// print writes obj pretty-printed to the width of the session.
+func (s *session) print(obj object.Object) {
+	io.WriteString(s.out, object.Pretty(obj, s.width)+"\n")
+}
+
+func (s *session) printError(err error) {
+	var parseErr *interpreter.ParseError
+	var macroErr *interpreter.MacroError
+	var resolveErr *interpreter.ResolveError
+	var typeErr *interpreter.TypeError
+	switch {
+	case errors.As(err, &parseErr):
+		printParseErrors(s.out, parseErr.Messages)
+	case errors.As(err, &macroErr):
+		printMacroErrors(s.out, macroErr.Diagnostics)
+	case errors.As(err, &resolveErr):
+		for _, d := range resolveErr.Diagnostics {
+			io.WriteString(s.out, "\t"+d.String()+"\n")
+		}
+	case errors.As(err, &typeErr):
+		for _, d := range typeErr.Diagnostics {
+			io.WriteString(s.out, "\t"+d.String()+"\n")
+		}
+	default:
+		io.WriteString(s.out, "\t"+err.Error()+"\n")
+	}
+}
+
+func printMacroErrors(out io.Writer, diagnostics []evaluator.Diagnostic) {
+	for _, d := range diagnostics {
+		io.WriteString(out, "\t"+d.String()+"\n")
+	}
+}
+
+func printParseErrors(out io.Writer, errors []string) {
+	for _, msg := range errors {
+		io.WriteString(out, "\t"+msg+"\n")
+	}
+}
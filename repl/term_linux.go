@@ -0,0 +1,10 @@
+//repl/term_linux.go
+
+package repl
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
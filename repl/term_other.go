@@ -0,0 +1,25 @@
+//repl/term_other.go
+
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package repl
+
+import "errors"
+
+type termState struct{}
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func restoreTerminal(fd int, state *termState) error {
+	return nil
+}
+
+func terminalWidth(fd int) int {
+	return 80
+}
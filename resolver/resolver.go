@@ -0,0 +1,333 @@
+// resolver/resolver.go
+
+// Package resolver works out statically what every identifier of a program
+// refers to. It annotates each ast.Identifier with an ast.Symbol telling
+// whether the name is local, bound in an enclosing function, global or a
+// builtin, which node binds it and where to find it at runtime. Function
+// literals get the ast.Scope of their bodies, so that calls can allocate
+// their slots. Identifiers that would not be found when evaluated are
+// reported.
+//
+// The scopes follow evaluation: the program and each function or macro
+// body have one, and blocks share the scope they are in. Within a scope a
+// let binds its name from the end of the statement on, while a function
+// body sees every name its enclosing scopes bind, since it runs later.
+//
+// Programs with syntax errors can be resolved too, as far as the parser
+// made sense of them, for tools that analyze code as it is typed.
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+)
+
+// Diagnostic is an identifier that would not be found when evaluated.
+type Diagnostic struct {
+	Line, Column int
+	Message      string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// Table is the result of resolving a program.
+type Table struct {
+	// Scopes maps the program and every function and macro literal in it
+	// to its scope.
+	Scopes map[ast.Node]*ast.Scope
+
+	// Shadowed maps every let or import name and parameter that hides a
+	// binding of an enclosing scope to that binding.
+	Shadowed map[*ast.Identifier]*ast.Identifier
+
+	Diagnostics []Diagnostic
+}
+
+// Resolver resolves the identifiers of programs.
+type Resolver struct {
+	// Globals reports whether a name is bound before the program runs, as
+	// by an earlier input of a REPL session. It may be nil.
+	Globals func(name string) bool
+
+	// Builtins reports whether a name is a builtin function or a special
+	// form. It may be nil.
+	Builtins func(name string) bool
+
+	// LateGlobals lets functions refer to names bound nowhere, taking them
+	// to be globals that later code defines before the function is called.
+	LateGlobals bool
+
+	// Forward resolves a name used before the let that binds it in the
+	// same scope to that let, as tools navigating the code expect. The use
+	// is still reported.
+	Forward bool
+}
+
+// Resolve annotates the identifiers of program and returns its scopes and
+// the identifiers that could not be resolved.
+func (r *Resolver) Resolve(program *ast.Program) *Table {
+	rs := &resolution{Resolver: r, table: &Table{
+		Scopes:   make(map[ast.Node]*ast.Scope),
+		Shadowed: make(map[*ast.Identifier]*ast.Identifier),
+	}}
+	top := rs.newScope(program, nil, nil)
+	rs.declare(top, program.Statements)
+	rs.statements(top, program.Statements)
+	return rs.table
+}
+
+// scope is an ast.Scope and what is known about its names while resolving.
+type scope struct {
+	*ast.Scope
+	parent   *scope
+	slots    map[string]int
+	lets     map[string]*ast.Identifier // the first let binding each name
+	bound    map[string]*ast.Identifier // the latest binding of each name so far
+	function bool
+}
+
+type resolution struct {
+	*Resolver
+	table *Table
+}
+
+func (rs *resolution) newScope(node ast.Node, parent *scope, params []*ast.Identifier) *scope {
+	s := &scope{
+		Scope:    &ast.Scope{},
+		parent:   parent,
+		slots:    make(map[string]int),
+		lets:     make(map[string]*ast.Identifier),
+		bound:    make(map[string]*ast.Identifier),
+		function: parent != nil,
+	}
+	s.Scope.Slots = s.slots
+	if parent != nil {
+		s.Scope.Parent = parent.Scope
+	}
+	rs.table.Scopes[node] = s.Scope
+	for _, param := range params {
+		rs.bind(s, param)
+	}
+	return s
+}
+
+// kind returns the kind of the names bound in s, as seen from s itself.
+func (s *scope) kind() ast.SymbolKind {
+	if s.function {
+		return ast.LOCAL
+	}
+	return ast.GLOBAL
+}
+
+// slot returns the slot of name in s, adding it if it is new.
+func (s *scope) slot(name string) int {
+	if slot, ok := s.slots[name]; ok {
+		return slot
+	}
+	s.slots[name] = len(s.Names)
+	s.Names = append(s.Names, name)
+	return s.slots[name]
+}
+
+// bind makes ident, a let or import name or a parameter, the binding of its
+// name in s.
+func (rs *resolution) bind(s *scope, ident *ast.Identifier) {
+	if ident == nil {
+		return
+	}
+	for sc := s.parent; sc != nil; sc = sc.parent {
+		if def, ok := sc.lookup(ident.Value, true); ok {
+			rs.table.Shadowed[ident] = def
+			break
+		}
+	}
+	ident.Symbol = &ast.Symbol{Name: ident.Value, Kind: s.kind(), Def: ident, Slot: s.slot(ident.Value)}
+	s.bound[ident.Value] = ident
+}
+
+// declare gives the let and import bindings in statements that belong to
+// s their slots, leaving out those inside function and macro literals.
+func (rs *resolution) declare(s *scope, statements []ast.Statement) {
+	for _, stmt := range statements {
+		ast.Walk(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.LetStatement:
+				s.declare(node.Name)
+			case *ast.ImportStatement:
+				s.declare(node.Name)
+			case *ast.FunctionLiteral, *ast.MacroLiteral:
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// declare gives the let or import name ident its slot in s.
+func (s *scope) declare(ident *ast.Identifier) {
+	if ident == nil {
+		return
+	}
+	s.slot(ident.Value)
+	if _, ok := s.lets[ident.Value]; !ok {
+		s.lets[ident.Value] = ident
+	}
+}
+
+// lookup returns the binding of name in s: the latest so far, or if there
+// is none and later is set, the first let binding it.
+func (s *scope) lookup(name string, later bool) (*ast.Identifier, bool) {
+	if def, ok := s.bound[name]; ok {
+		return def, true
+	}
+	if later {
+		def, ok := s.lets[name]
+		return def, ok
+	}
+	return nil, false
+}
+
+func (rs *resolution) statements(s *scope, statements []ast.Statement) {
+	for _, stmt := range statements {
+		rs.statement(s, stmt)
+	}
+}
+
+func (rs *resolution) statement(s *scope, stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		rs.expression(s, stmt.Value)
+		rs.bind(s, stmt.Name)
+	case *ast.ImportStatement:
+		rs.bind(s, stmt.Name)
+	case *ast.ReturnStatement:
+		rs.expression(s, stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		rs.expression(s, stmt.Expression)
+	case *ast.BlockStatement:
+		rs.statements(s, stmt.Statements)
+	}
+}
+
+func (rs *resolution) expression(s *scope, e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		if e != nil {
+			rs.resolve(s, e)
+		}
+	case *ast.PrefixExpression:
+		rs.expression(s, e.Right)
+	case *ast.InfixExpression:
+		rs.expression(s, e.Left)
+		rs.expression(s, e.Right)
+	case *ast.IfExpression:
+		rs.expression(s, e.Condition)
+		if e.Consequence != nil {
+			rs.statements(s, e.Consequence.Statements)
+		}
+		if e.Alternative != nil {
+			rs.statements(s, e.Alternative.Statements)
+		}
+	case *ast.FunctionLiteral:
+		e.Scope = rs.function(s, e, e.Parameters, e.Body)
+	case *ast.MacroLiteral:
+		rs.function(s, e, e.Parameters, e.Body)
+	case *ast.CallExpression:
+		rs.expression(s, e.Function)
+		if e.Function.TokenLiteral() == "quote" {
+			rs.quoted(s, e.Arguments)
+			return
+		}
+		for _, arg := range e.Arguments {
+			rs.expression(s, arg)
+		}
+	case *ast.IndexExpression:
+		rs.expression(s, e.Left)
+		rs.expression(s, e.Index)
+	case *ast.ArrayLiteral:
+		for _, elem := range e.Elements {
+			rs.expression(s, elem)
+		}
+	case *ast.HashLiteral:
+		for _, key := range e.Keys() {
+			rs.expression(s, key)
+			rs.expression(s, e.Pairs[key])
+		}
+	}
+}
+
+// quoted resolves the arguments of the unquote calls in the arguments of a
+// quote call, the only parts of them that are evaluated.
+func (rs *resolution) quoted(s *scope, args []ast.Expression) {
+	for _, arg := range args {
+		ast.Walk(arg, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+			switch call.Function.TokenLiteral() {
+			case "unquote", "unquote_splice":
+				rs.expression(s, call.Function)
+				for _, arg := range call.Arguments {
+					rs.expression(s, arg)
+				}
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func (rs *resolution) function(parent *scope, node ast.Node, params []*ast.Identifier, body *ast.BlockStatement) *ast.Scope {
+	s := rs.newScope(node, parent, params)
+	if body == nil {
+		return s.Scope
+	}
+	rs.declare(s, body.Statements)
+	rs.statements(s, body.Statements)
+	return s.Scope
+}
+
+// resolve annotates ident, used in s, with the symbol it refers to.
+func (rs *resolution) resolve(s *scope, ident *ast.Identifier) {
+	name := ident.Value
+	depth := 0
+	for sc := s; sc != nil; sc = sc.parent {
+		// Code in a function runs after the let statements that follow
+		// it in the enclosing scopes.
+		if def, ok := sc.lookup(name, sc != s); ok {
+			kind := ast.CLOSURE
+			switch {
+			case !sc.function:
+				kind = ast.GLOBAL
+			case depth == 0:
+				kind = ast.LOCAL
+			}
+			ident.Symbol = &ast.Symbol{Name: name, Kind: kind, Def: def, Depth: depth, Slot: sc.slots[name]}
+			return
+		}
+		depth++
+	}
+	depth--
+
+	switch {
+	case rs.Globals != nil && rs.Globals(name):
+		ident.Symbol = &ast.Symbol{Name: name, Kind: ast.GLOBAL, Depth: depth, Slot: -1}
+	case rs.Builtins != nil && rs.Builtins(name):
+		ident.Symbol = &ast.Symbol{Name: name, Kind: ast.BUILTIN, Slot: -1}
+	case rs.LateGlobals && s.function:
+		ident.Symbol = &ast.Symbol{Name: name, Kind: ast.GLOBAL, Depth: depth, Slot: -1}
+	default:
+		ident.Symbol = nil
+		if def, ok := s.lets[name]; ok && rs.Forward {
+			ident.Symbol = &ast.Symbol{Name: name, Kind: s.kind(), Def: def, Slot: s.slots[name]}
+		}
+		rs.table.Diagnostics = append(rs.table.Diagnostics, Diagnostic{
+			Line:    ident.Token.Line,
+			Column:  ident.Token.Column,
+			Message: "identifier not found: " + name,
+		})
+	}
+}
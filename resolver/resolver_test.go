@@ -0,0 +1,203 @@
+// resolver/resolver_test.go
+
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func resolve(t *testing.T, input string, r *Resolver) (*ast.Program, *Table) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program, r.Resolve(program)
+}
+
+// symbols describes the symbol of every identifier in program, in source
+// order, as "line:column name kind depth slot def".
+func symbols(program *ast.Program) []string {
+	var out []string
+	ast.Walk(program, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		desc := fmt.Sprintf("%d:%d %s", ident.Token.Line, ident.Token.Column, ident.Value)
+		if sym := ident.Symbol; sym != nil {
+			desc += fmt.Sprintf(" %s %d %d", sym.Kind, sym.Depth, sym.Slot)
+			if sym.Def != nil {
+				desc += fmt.Sprintf(" %d:%d", sym.Def.Token.Line, sym.Def.Token.Column)
+			}
+		}
+		out = append(out, desc)
+		return true
+	})
+	return out
+}
+
+func TestResolve(t *testing.T) {
+	input := `let x = 1;
+let f = fn(a) { let y = a + x; fn(b) { a + b + y + g() } };
+let g = fn() { len(x) };`
+
+	builtins := func(name string) bool { return name == "len" }
+	program, table := resolve(t, input, &Resolver{Builtins: builtins})
+	if len(table.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", table.Diagnostics)
+	}
+
+	expected := []string{
+		"1:5 x global 0 0 1:5",
+		"2:5 f global 0 1 2:5",
+		"2:12 a local 0 0 2:12",
+		"2:21 y local 0 1 2:21",
+		"2:25 a local 0 0 2:12",
+		"2:29 x global 1 0 1:5",
+		"2:35 b local 0 0 2:35",
+		"2:40 a closure 1 0 2:12",
+		"2:44 b local 0 0 2:35",
+		"2:48 y closure 1 1 2:21",
+		"2:52 g global 2 2 3:5",
+		"3:5 g global 0 2 3:5",
+		"3:16 len builtin 0 -1",
+		"3:20 x global 1 0 1:5",
+	}
+	got := symbols(program)
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong symbols.\nwant=%s\ngot= %s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	fn := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if names := table.Scopes[fn].Names; strings.Join(names, " ") != "a y" {
+		t.Errorf("wrong names in the scope of f. got=%v", names)
+	}
+	if names := table.Scopes[program].Names; strings.Join(names, " ") != "x f g" {
+		t.Errorf("wrong names in the top-level scope. got=%v", names)
+	}
+}
+
+func TestResolveShadowing(t *testing.T) {
+	input := `let len = fn(x) { x };
+let f = fn(x) { let x = x + 1; if (x) { let z = 2 }; z };
+len(1);`
+
+	program, table := resolve(t, input, &Resolver{Builtins: func(string) bool { return true }})
+	if len(table.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", table.Diagnostics)
+	}
+
+	expected := []string{
+		"1:5 len global 0 0 1:5",
+		"1:14 x local 0 0 1:14",
+		"1:19 x local 0 0 1:14",
+		"2:5 f global 0 1 2:5",
+		"2:12 x local 0 0 2:12",
+		"2:21 x local 0 0 2:21",
+		"2:25 x local 0 0 2:12",
+		"2:36 x local 0 0 2:21",
+		"2:45 z local 0 1 2:45",
+		"2:54 z local 0 1 2:45",
+		"3:1 len global 0 0 1:5",
+	}
+	got := symbols(program)
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong symbols.\nwant=%s\ngot= %s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+}
+
+func TestResolveShadowed(t *testing.T) {
+	program, table := resolve(t, "let x = 1; let f = fn(x) { fn(y) { let x = y; x } };", &Resolver{})
+	fn := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	inner := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	innerLet := inner.Body.Statements[0].(*ast.LetStatement)
+
+	topX := program.Statements[0].(*ast.LetStatement).Name
+	if got := table.Shadowed[fn.Parameters[0]]; got != topX {
+		t.Errorf("the parameter x does not hide the global x. got=%v", got)
+	}
+	if got := table.Shadowed[innerLet.Name]; got != fn.Parameters[0] {
+		t.Errorf("the inner let x does not hide the parameter x. got=%v", got)
+	}
+	if got, ok := table.Shadowed[inner.Parameters[0]]; ok {
+		t.Errorf("the parameter y hides %v", got)
+	}
+}
+
+func TestResolveForward(t *testing.T) {
+	program, table := resolve(t, "y; let y = 1;", &Resolver{Forward: true})
+	if got := diagnostics(table); got != "1:1: identifier not found: y" {
+		t.Errorf("wrong diagnostics. got=%s", got)
+	}
+	if got := symbols(program)[0]; got != "1:1 y global 0 0 1:8" {
+		t.Errorf("wrong symbol for a forward use. got=%s", got)
+	}
+}
+
+func TestResolveQuote(t *testing.T) {
+	input := `let a = 1; quote(b + unquote(a + c));`
+
+	program, table := resolve(t, input, &Resolver{Builtins: func(name string) bool {
+		return name == "quote" || name == "unquote"
+	}})
+
+	expected := []string{"1:34: identifier not found: c"}
+	if got := diagnostics(table); got != strings.Join(expected, "\n") {
+		t.Errorf("wrong diagnostics.\nwant=%s\ngot= %s", strings.Join(expected, "\n"), got)
+	}
+	for _, desc := range symbols(program) {
+		if strings.HasPrefix(desc, "1:18 b ") {
+			t.Errorf("quoted identifier was resolved: %s", desc)
+		}
+	}
+}
+
+func TestResolveDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		resolver Resolver
+		expected []string
+	}{
+		{"foobar", Resolver{}, []string{"1:1: identifier not found: foobar"}},
+		{"let x = x;", Resolver{}, []string{"1:9: identifier not found: x"}},
+		{"y; let y = 1; y", Resolver{}, []string{"1:1: identifier not found: y"}},
+		{"let f = fn() { y; let y = 1; }", Resolver{}, []string{"1:16: identifier not found: y"}},
+		{"let f = fn() { g() }; let g = fn() { 1 };", Resolver{}, nil},
+		{"let f = fn() { h() };", Resolver{}, []string{"1:16: identifier not found: h"}},
+		{"let f = fn() { h() };\nh", Resolver{LateGlobals: true}, []string{"2:1: identifier not found: h"}},
+		{"x + len(x)", Resolver{Globals: func(name string) bool { return name == "x" }}, []string{
+			"1:5: identifier not found: len",
+		}},
+	}
+
+	for _, tt := range tests {
+		_, table := resolve(t, tt.input, &tt.resolver)
+		if got := diagnostics(table); got != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong diagnostics for %q.\nwant=%s\ngot= %s", tt.input, strings.Join(tt.expected, "\n"), got)
+		}
+	}
+}
+
+func TestResolveLateGlobals(t *testing.T) {
+	program, _ := resolve(t, "let f = fn() { fn() { h } };", &Resolver{LateGlobals: true})
+	got := symbols(program)
+	if got[len(got)-1] != "1:23 h global 2 -1" {
+		t.Errorf("wrong symbol for a late global. got=%s", got[len(got)-1])
+	}
+}
+
+func diagnostics(table *Table) string {
+	lines := make([]string, len(table.Diagnostics))
+	for i, d := range table.Diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
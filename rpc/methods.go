@@ -0,0 +1,231 @@
+// rpc/methods.go
+
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"monkey/ast"
+	"monkey/interpreter"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+)
+
+type method func(s *Server, params json.RawMessage) (interface{}, *Error)
+
+var methods = map[string]method{
+	"parse":    (*Server).parse,
+	"eval":     (*Server).eval,
+	"tokens":   (*Server).tokens,
+	"complete": (*Server).complete,
+	"reset":    (*Server).reset,
+}
+
+// call runs the method name. A method that panics, which is a bug in the
+// interpreter, fails with an internal error, so that one request cannot stop
+// the server.
+func (s *Server) call(name string, params json.RawMessage) (result interface{}, err *Error) {
+	m, ok := methods[name]
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + name}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &Error{Code: CodeInternalError, Message: fmt.Sprintf("internal error: %v", r)}
+		}
+	}()
+	return m(s, params)
+}
+
+// decodeParams unmarshals params, which may be absent, into v.
+func decodeParams(params json.RawMessage, v interface{}) *Error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+type sourceParams struct {
+	Source string `json:"source"`
+}
+
+type sessionParams struct {
+	Session string `json:"session"`
+	Source  string `json:"source"`
+	Prefix  string `json:"prefix"`
+}
+
+// ParseResult is the result of parse. AST is present even if there were
+// errors, holding whatever the parser made of the source.
+type ParseResult struct {
+	AST    json.RawMessage `json:"ast"`
+	Errors []string        `json:"errors"`
+}
+
+func (s *Server) parse(params json.RawMessage) (interface{}, *Error) {
+	var p sourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	ps := parser.New(lexer.New(p.Source))
+	program := ps.ParseProgram()
+	tree, err := ast.MarshalJSON(program)
+	if err != nil {
+		return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+	errs := ps.Errors()
+	if errs == nil {
+		errs = []string{}
+	}
+	return ParseResult{AST: tree, Errors: errs}, nil
+}
+
+// EvalResult is the result of eval. Value is the repr of the result and
+// both fields are empty if the source evaluated to nothing.
+type EvalResult struct {
+	Value *string `json:"value"`
+	Type  string  `json:"type,omitempty"`
+}
+
+func (s *Server) eval(params json.RawMessage) (interface{}, *Error) {
+	var p sessionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	sess := s.session(p.Session)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	evaluated, err := sess.interp.Run(p.Source)
+	if err != nil {
+		return nil, evalError(err)
+	}
+	if evaluated == nil {
+		return EvalResult{}, nil
+	}
+	repr := evaluated.Repr()
+	return EvalResult{Value: &repr, Type: string(evaluated.Type())}, nil
+}
+
+// evalError turns an error from the interpreter into an Error whose data
+// tells what kind of error it was and where.
+func evalError(err error) *Error {
+	var parseErr *interpreter.ParseError
+	var macroErr *interpreter.MacroError
+	var resolveErr *interpreter.ResolveError
+	var typeErr *interpreter.TypeError
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &parseErr):
+		return &Error{Code: CodeSourceError, Message: err.Error(), Data: map[string]interface{}{
+			"kind":     "parse",
+			"messages": parseErr.Messages,
+		}}
+	case errors.As(err, &macroErr):
+		diagnostics := []map[string]interface{}{}
+		for _, d := range macroErr.Diagnostics {
+			diagnostics = append(diagnostics, map[string]interface{}{
+				"macro":   d.Macro,
+				"line":    d.Line,
+				"column":  d.Column,
+				"message": d.Message,
+			})
+		}
+		return &Error{Code: CodeMacroError, Message: err.Error(), Data: map[string]interface{}{
+			"kind":        "macro",
+			"diagnostics": diagnostics,
+		}}
+	case errors.As(err, &resolveErr):
+		diagnostics := []map[string]interface{}{}
+		for _, d := range resolveErr.Diagnostics {
+			diagnostics = append(diagnostics, map[string]interface{}{
+				"line":    d.Line,
+				"column":  d.Column,
+				"message": d.Message,
+			})
+		}
+		return &Error{Code: CodeSourceError, Message: err.Error(), Data: map[string]interface{}{
+			"kind":        "resolve",
+			"diagnostics": diagnostics,
+		}}
+	case errors.As(err, &typeErr):
+		diagnostics := []map[string]interface{}{}
+		for _, d := range typeErr.Diagnostics {
+			diagnostics = append(diagnostics, map[string]interface{}{
+				"line":    d.Line,
+				"column":  d.Column,
+				"message": d.Message,
+			})
+		}
+		return &Error{Code: CodeSourceError, Message: err.Error(), Data: map[string]interface{}{
+			"kind":        "type",
+			"diagnostics": diagnostics,
+		}}
+	case errors.As(err, &runtimeErr):
+		return &Error{Code: CodeRuntimeError, Message: err.Error(), Data: map[string]interface{}{
+			"kind":    "runtime",
+			"message": runtimeErr.Err.Message,
+		}}
+	default:
+		return &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+}
+
+// Token is a token in the result of tokens.
+type Token struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Line    int             `json:"line"`
+	Column  int             `json:"column"`
+}
+
+func (s *Server) tokens(params json.RawMessage) (interface{}, *Error) {
+	var p sourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	result := []Token{}
+	l := lexer.New(p.Source)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		result = append(result, Token{Type: tok.Type, Literal: tok.Literal, Line: tok.Line, Column: tok.Column})
+	}
+	return result, nil
+}
+
+func (s *Server) complete(params json.RawMessage) (interface{}, *Error) {
+	var p sessionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	sess := s.session(p.Session)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	names := sess.interp.Complete(p.Prefix)
+	if names == nil {
+		names = []string{}
+	}
+	return names, nil
+}
+
+func (s *Server) reset(params json.RawMessage) (interface{}, *Error) {
+	var p sessionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, p.Session)
+	s.mu.Unlock()
+	return struct{}{}, nil
+}
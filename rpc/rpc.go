@@ -0,0 +1,192 @@
+// rpc/rpc.go
+
+// Package rpc serves the lexer, parser and interpreter over JSON-RPC 2.0, so
+// that tools can work with Monkey code without scraping REPL output.
+//
+// Messages are JSON values separated by newlines, in either direction. The
+// methods are:
+//
+//	parse    {"source"}            -> {"ast", "errors"}
+//	eval     {"session", "source"} -> {"value", "type"}
+//	tokens   {"source"}            -> [{"type", "literal", "line", "column"}]
+//	complete {"session", "prefix"} -> ["name", ...]
+//	reset    {"session"}           -> {}
+//
+// Sessions are named by the client and created on first use; bindings made
+// by eval in a session are seen by later calls naming the same session.
+// Parse, macro and runtime errors from eval are reported as JSON-RPC errors
+// with the codes below and the details in their data.
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"monkey/interpreter"
+	"net"
+	"sync"
+)
+
+// Error codes defined by JSON-RPC 2.0.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error codes for errors in the Monkey code passed to eval.
+const (
+	CodeSourceError  = -32000
+	CodeMacroError   = -32001
+	CodeRuntimeError = -32002
+)
+
+// Request is a JSON-RPC request. A request without an ID is a notification
+// and gets no response.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response, holding either a result or an error.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Server answers requests against a set of interpreter sessions shared by
+// all of its connections.
+type Server struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is an interpreter and the lock that keeps calls to it in turn.
+type session struct {
+	mu     sync.Mutex
+	interp *interpreter.Interpreter
+}
+
+func NewServer() *Server {
+	return &Server{sessions: make(map[string]*session)}
+}
+
+// session returns the session named id, creating it if needed.
+func (s *Server) session(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		sess = &session{interp: interpreter.New()}
+		s.sessions[id] = sess
+	}
+	return sess
+}
+
+// Serve accepts connections on l and serves each with ServeConn until l is
+// closed, when it returns nil.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			s.ServeConn(conn, conn)
+		}()
+	}
+}
+
+// ServeConn reads requests from r and writes the responses to w until r
+// ends, when it returns nil, or holds something that is not JSON.
+func (s *Server) ServeConn(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	enc := json.NewEncoder(w)
+	for {
+		var msg json.RawMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			enc.Encode(errorResponse(nil, &Error{Code: CodeParseError, Message: "parse error"}))
+			return err
+		}
+
+		if response := s.handleMessage(msg); response != nil {
+			if err := enc.Encode(response); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleMessage answers a single request or a batch. It returns nil if
+// there is nothing to send back.
+func (s *Server) handleMessage(msg json.RawMessage) interface{} {
+	if trimmed := bytes.TrimSpace(msg); len(trimmed) == 0 || trimmed[0] != '[' {
+		if response := s.handle(msg); response != nil {
+			return response
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(msg, &batch); err != nil || len(batch) == 0 {
+		return errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+	var responses []*Response
+	for _, msg := range batch {
+		if response := s.handle(msg); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// handle answers one request, returning nil for notifications.
+func (s *Server) handle(msg json.RawMessage) *Response {
+	var req Request
+	if err := json.Unmarshal(msg, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	result, err := s.call(req.Method, req.Params)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, err)
+	}
+	return &Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	return &Response{JSONRPC: "2.0", ID: id, Error: err}
+}
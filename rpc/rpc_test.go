@@ -0,0 +1,217 @@
+// rpc/rpc_test.go
+
+package rpc
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+// exchange sends each request to a new connection of s and returns the
+// response lines.
+func exchange(t *testing.T, s *Server, requests ...string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.ServeConn(strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatalf("ServeConn returned %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		request  string
+		response string
+	}{
+		{
+			`{"jsonrpc": "2.0", "id": 1, "method": "eval", "params": {"source": "let a = [\"x\", 1]; a"}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"value":"[\"x\", 1]","type":"ARRAY"}}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 2, "method": "eval", "params": {"source": "let b = 2;"}}`,
+			`{"jsonrpc":"2.0","id":2,"result":{"value":null}}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": "s", "method": "eval", "params": {"session": "other", "source": "a"}}`,
+			`{"jsonrpc":"2.0","id":"s","error":{"code":-32000,"message":"1:1: identifier not found: a","data":` +
+				`{"diagnostics":[{"column":1,"line":1,"message":"identifier not found: a"}],"kind":"resolve"}}}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": "t", "method": "eval", "params": {"session": "other", "source": "1 + true"}}`,
+			`{"jsonrpc":"2.0","id":"t","error":{"code":-32002,"message":"type mismatch: INTEGER + BOOLEAN",` +
+				`"data":{"kind":"runtime","message":"type mismatch: INTEGER + BOOLEAN"}}}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 3, "method": "complete", "params": {"prefix": "a"}}`,
+			`{"jsonrpc":"2.0","id":3,"result":["a","assert","assert_eq","assert_error"]}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 4, "method": "reset"}`,
+			`{"jsonrpc":"2.0","id":4,"result":{}}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 5, "method": "complete", "params": {"prefix": "a"}}`,
+			`{"jsonrpc":"2.0","id":5,"result":["assert","assert_eq","assert_error"]}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 6, "method": "tokens", "params": {"source": "x +\n1"}}`,
+			`{"jsonrpc":"2.0","id":6,"result":[` +
+				`{"type":"IDENT","literal":"x","line":1,"column":1},` +
+				`{"type":"+","literal":"+","line":1,"column":3},` +
+				`{"type":"INT","literal":"1","line":2,"column":1}]}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 7, "method": "parse", "params": {"source": "-x"}}`,
+			`{"jsonrpc":"2.0","id":7,"result":{"ast":{"statements":[{"column":1,"expression":` +
+				`{"column":1,"line":1,"operator":"-","right":{"column":2,"line":1,"type":"Identifier","value":"x"},"type":"PrefixExpression"},` +
+				`"line":1,"type":"ExpressionStatement"}],"type":"Program"},"errors":[]}}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 8, "method": "nope"}`,
+			`{"jsonrpc":"2.0","id":8,"error":{"code":-32601,"message":"method not found: nope"}}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "id": 9, "method": "eval", "params": [1]}`,
+			`{"jsonrpc":"2.0","id":9,"error":{"code":-32602,"message":"invalid params: json: cannot unmarshal array into Go value of type rpc.sessionParams"}}`,
+		},
+		{
+			`{"id": 10, "method": "eval"}`,
+			`{"jsonrpc":"2.0","id":10,"error":{"code":-32600,"message":"invalid request"}}`,
+		},
+	}
+
+	s := NewServer()
+	for _, tt := range tests {
+		got := exchange(t, s, tt.request)
+		if len(got) != 1 || got[0] != tt.response {
+			t.Errorf("wrong response to %s\nwant=%s\ngot=%s", tt.request, tt.response, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		response string
+	}{
+		{
+			`let = 1`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"expected next token to be IDENT, got = instead\nno prefix parse function for = found",` +
+				`"data":{"kind":"parse","messages":["expected next token to be IDENT, got = instead","no prefix parse function for = found"]}}}`,
+		},
+		{
+			`let m = macro(x) { 1 }; m()`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"1:25: macro m: wrong number of arguments. got=0, want=1",` +
+				`"data":{"diagnostics":[{"column":25,"line":1,"macro":"m","message":"wrong number of arguments. got=0, want=1"}],"kind":"macro"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		request := `{"jsonrpc": "2.0", "id": 1, "method": "eval", "params": {"source": "` + tt.source + `"}}`
+		got := exchange(t, NewServer(), request)
+		if len(got) != 1 || got[0] != tt.response {
+			t.Errorf("wrong response to %s\nwant=%s\ngot=%s", tt.source, tt.response, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestArityErrors(t *testing.T) {
+	// Calls with the wrong number of arguments are ordinary runtime errors,
+	// and the session is still usable after them.
+	s := NewServer()
+	tests := []struct {
+		source   string
+		response string
+	}{
+		{
+			`let f = fn(x) { x }; f()`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"wrong number of arguments. got=0, want=1",` +
+				`"data":{"kind":"runtime","message":"wrong number of arguments. got=0, want=1"}}}`,
+		},
+		{
+			`quote()`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"wrong number of arguments. got=0, want=1",` +
+				`"data":{"kind":"runtime","message":"wrong number of arguments. got=0, want=1"}}}`,
+		},
+		{
+			`1 + 1`,
+			`{"jsonrpc":"2.0","id":1,"result":{"value":"2","type":"INTEGER"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		request := `{"jsonrpc": "2.0", "id": 1, "method": "eval", "params": {"source": "` + tt.source + `"}}`
+		got := exchange(t, s, request)
+		if len(got) != 1 || got[0] != tt.response {
+			t.Errorf("wrong response to %s\nwant=%s\ngot=%s", tt.source, tt.response, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestBatchesAndNotifications(t *testing.T) {
+	got := exchange(t, NewServer(),
+		`{"jsonrpc": "2.0", "method": "eval", "params": {"source": "let x = 1;"}}`,
+		`[{"jsonrpc": "2.0", "id": 1, "method": "eval", "params": {"source": "x + 1"}},`+
+			` {"jsonrpc": "2.0", "method": "reset"},`+
+			` {"jsonrpc": "2.0", "id": 2, "method": "complete", "params": {"prefix": "x"}}]`,
+		`[{"jsonrpc": "2.0", "method": "reset"}]`,
+		`[]`,
+	)
+
+	expected := []string{
+		`[{"jsonrpc":"2.0","id":1,"result":{"value":"2","type":"INTEGER"}},{"jsonrpc":"2.0","id":2,"result":[]}]`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong responses.\nwant=%s\ngot=%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestMalformedJSON(t *testing.T) {
+	var out bytes.Buffer
+	err := NewServer().ServeConn(strings.NewReader(`{"jsonrpc": `), &out)
+	if err == nil {
+		t.Errorf("ServeConn did not fail on malformed JSON")
+	}
+
+	expected := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}` + "\n"
+	if out.String() != expected {
+		t.Errorf("wrong response.\nwant=%s\ngot=%s", expected, out.String())
+	}
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := NewServer()
+	done := make(chan error)
+	go func() { done <- s.Serve(l) }()
+	defer func() {
+		l.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	// Sessions outlive the connections that use them.
+	for i, source := range []string{"let x = 20;", "x + 1"} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		conn.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "eval", "params": {"source": "` + source + `"}}` + "\n"))
+		conn.(*net.TCPConn).CloseWrite()
+
+		var out bytes.Buffer
+		out.ReadFrom(conn)
+		conn.Close()
+
+		if i == 1 && out.String() != `{"jsonrpc":"2.0","id":1,"result":{"value":"21","type":"INTEGER"}}`+"\n" {
+			t.Errorf("wrong response: %s", out.String())
+		}
+	}
+}
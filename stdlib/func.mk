@@ -0,0 +1,29 @@
+// std/func: functions that build functions.
+
+// identity returns x.
+export let identity = fn(x) {
+    x;
+};
+
+// compose returns the function that calls g and then f on its result.
+export let compose = fn(f, g) {
+    fn(x) {
+        f(g(x));
+    };
+};
+
+// partial returns the function of one argument that calls the two
+// argument function f with a first.
+export let partial = fn(f, a) {
+    fn(b) {
+        f(a, b);
+    };
+};
+
+// flip returns the function that calls the two argument function f with
+// its arguments swapped.
+export let flip = fn(f) {
+    fn(a, b) {
+        f(b, a);
+    };
+};
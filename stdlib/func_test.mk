@@ -0,0 +1,30 @@
+// Tests of std/func.
+
+import "std/func" as func;
+
+let inc = fn(x) {
+    x + 1;
+};
+let double = fn(x) {
+    x * 2;
+};
+let sub = fn(a, b) {
+    a - b;
+};
+
+let test_identity = fn() {
+    assert_eq(func["identity"]([1, "a"]), [1, "a"]);
+};
+
+let test_compose = fn() {
+    assert_eq(func["compose"](inc, double)(5), 11);
+    assert_eq(func["compose"](double, inc)(5), 12);
+};
+
+let test_partial = fn() {
+    assert_eq(func["partial"](sub, 10)(3), 7);
+};
+
+let test_flip = fn() {
+    assert_eq(func["flip"](sub)(10, 3), -7);
+};
@@ -0,0 +1,45 @@
+// std/list: functions over arrays. They take time linear in the length of
+// the array, being built on the Go functions of std/native.
+
+import "std/native" as native;
+
+// map returns the array of the results of calling f on each element of arr.
+export let map = fn(arr, f) {
+    native["map"](arr, f);
+};
+
+// filter returns the array of the elements of arr that keep is true of.
+export let filter = fn(arr, keep) {
+    native["filter"](arr, keep);
+};
+
+// reduce combines the elements of arr from the first to the last, calling
+// f with the result so far, starting with initial, and each element.
+export let reduce = fn(arr, initial, f) {
+    native["reduce"](arr, initial, f);
+};
+
+// any reports whether pred is true of some element of arr. It stops at the
+// first such element.
+export let any = fn(arr, pred) {
+    native["any"](arr, pred);
+};
+
+// all reports whether pred is true of every element of arr. It stops at
+// the first element it is false of.
+export let all = fn(arr, pred) {
+    !any(arr, fn(x) {
+        !pred(x);
+    });
+};
+
+// reverse returns the elements of arr in the opposite order.
+export let reverse = fn(arr) {
+    native["reverse"](arr);
+};
+
+// range returns the array of the integers from start up to, but not
+// including, end.
+export let range = fn(start, end) {
+    native["range"](start, end);
+};
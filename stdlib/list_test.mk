@@ -0,0 +1,55 @@
+// Tests of std/list.
+
+import "std/list" as list;
+
+let double = fn(x) {
+    x * 2;
+};
+let even = fn(x) {
+    x / 2 * 2 == x;
+};
+let add = fn(a, b) {
+    a + b;
+};
+
+let test_map = fn() {
+    assert_eq(list["map"]([1, 2, 3], double), [2, 4, 6]);
+    assert_eq(list["map"]([], double), []);
+    assert_eq(len(list["map"](list["range"](0, 100000), double)), 100000);
+    assert_error(list["map"]([1], fn(x) { x + true }), "type mismatch");
+    assert_error(list["map"](1, double), "must be ARRAY");
+};
+
+let test_filter = fn() {
+    assert_eq(list["filter"]([1, 2, 3, 4], even), [2, 4]);
+    assert_eq(list["filter"]([1, 3], even), []);
+};
+
+let test_reduce = fn() {
+    assert_eq(list["reduce"]([1, 2, 3, 4], 0, add), 10);
+    assert_eq(list["reduce"]([], 7, add), 7);
+    assert_eq(list["reduce"](list["range"](0, 100000), 0, add), 4999950000);
+};
+
+let test_any = fn() {
+    assert(list["any"]([1, 3, 4], even));
+    assert(!list["any"]([1, 3], even));
+    assert(!list["any"]([], even));
+};
+
+let test_all = fn() {
+    assert(list["all"]([2, 4], even));
+    assert(!list["all"]([2, 3, 4], even));
+    assert(list["all"]([], even));
+};
+
+let test_reverse = fn() {
+    assert_eq(list["reverse"]([1, 2, 3]), [3, 2, 1]);
+    assert_eq(list["reverse"]([]), []);
+    assert_eq(list["reverse"](list["range"](0, 100000))[0], 99999);
+};
+
+let test_range = fn() {
+    assert_eq(list["range"](2, 5), [2, 3, 4]);
+    assert_eq(list["range"](5, 2), []);
+};
@@ -0,0 +1,56 @@
+// stdlib/stdlib.go
+
+// Package stdlib holds the standard library of Monkey: modules written in
+// Monkey itself and embedded in the binary, so that every build of the
+// interpreter carries the library of the same version. A module is
+// imported by its name under std/, e.g.
+//
+//	import "std/list" as list;
+//
+// and is only parsed and evaluated when first imported.
+package stdlib
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Prefix starts the import paths of the standard library modules.
+const Prefix = "std/"
+
+// Native is the import path of the module of functions written in Go that
+// the modules of the standard library are built on. It has no source.
+const Native = Prefix + "native"
+
+// files holds the modules, listed one by one so that their tests are not
+// built into the interpreter.
+//
+//go:embed func.mk list.mk
+var files embed.FS
+
+// Source returns the source of the module imported as path, which starts
+// with Prefix, and reports whether there is one.
+func Source(importPath string) (string, bool) {
+	name, ok := strings.CutPrefix(importPath, Prefix)
+	if !ok || strings.Contains(name, "/") {
+		return "", false
+	}
+	src, err := files.ReadFile(name + ".mk")
+	if err != nil {
+		return "", false
+	}
+	return string(src), true
+}
+
+// Names returns the sorted import paths of the standard library modules.
+func Names() []string {
+	entries, _ := fs.ReadDir(files, ".")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, Prefix+strings.TrimSuffix(entry.Name(), ".mk"))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,62 @@
+// stdlib/stdlib_test.go
+
+package stdlib_test
+
+import (
+	"monkey/stdlib"
+	"monkey/tester"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	names := stdlib.Names()
+	expected := []string{"std/func", "std/list"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("wrong names. want=%v, got=%v", expected, names)
+	}
+
+	for _, path := range []string{"std/list_test", "std/missing", "list", "std/../list"} {
+		if _, ok := stdlib.Source(path); ok {
+			t.Errorf("expected no module at %q", path)
+		}
+	}
+}
+
+// TestEmbedded checks that every module in the directory is embedded, since
+// they are listed one by one.
+func TestEmbedded(t *testing.T) {
+	paths, err := filepath.Glob("*.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []string
+	for _, path := range paths {
+		if !strings.HasSuffix(path, "_test.mk") {
+			expected = append(expected, stdlib.Prefix+strings.TrimSuffix(path, ".mk"))
+		}
+	}
+	if names := stdlib.Names(); strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("wrong embedded modules. want=%v, got=%v", expected, names)
+	}
+}
+
+// TestModules runs the Monkey tests of the modules.
+func TestModules(t *testing.T) {
+	files, err := tester.Find([]string{"."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no tests found")
+	}
+
+	for _, file := range files {
+		for _, r := range tester.RunFile(file) {
+			if !r.Passed() {
+				t.Errorf("%s: %s: %s", r.Name, r.Position(), r.Failure)
+			}
+		}
+	}
+}
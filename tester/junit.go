@@ -0,0 +1,94 @@
+// tester/junit.go
+
+package tester
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results to w as a JUnit XML report, with a test suite
+// for each file. A file that failed to run is reported as a test named
+// after the file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitSuites{}
+	var total time.Duration
+	var durations []time.Duration
+	index := make(map[string]int)
+	for _, r := range results {
+		i, ok := index[r.File]
+		if !ok {
+			i = len(report.Suites)
+			index[r.File] = i
+			report.Suites = append(report.Suites, junitSuite{Name: r.File})
+			durations = append(durations, 0)
+		}
+		suite := &report.Suites[i]
+
+		c := junitCase{Name: r.Name, Classname: r.File, Time: seconds(r.Duration)}
+		if c.Name == "" {
+			c.Name = r.File
+		}
+		if !r.Passed() {
+			c.Failure = &junitFailure{Message: r.Failure, Text: r.Position() + ": " + r.Failure}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+		report.Tests++
+		durations[i] += r.Duration
+		total += r.Duration
+	}
+
+	for i := range report.Suites {
+		report.Suites[i].Time = seconds(durations[i])
+	}
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// seconds formats d as JUnit reports times, in seconds.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,230 @@
+// tester/tester.go
+
+// Package tester runs tests written in Monkey. A test is a function of no
+// parameters bound by a top-level let whose name starts with test_, in a
+// file whose name ends in _test.mk:
+//
+//	let test_add = fn() {
+//	    assert_eq(1 + 2, 3);
+//	};
+//
+// The file is run first, and then each test in the order they are
+// written, all in the same environment. A test fails if calling it fails,
+// usually because of an assert, assert_eq or assert_error.
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"monkey/ast"
+	"monkey/interpreter"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Suffix ends the names of test files.
+const Suffix = "_test.mk"
+
+// Prefix starts the names of tests.
+const Prefix = "test_"
+
+// Result is the outcome of a test, or of running a test file if Name is
+// empty.
+type Result struct {
+	File     string
+	Name     string
+	Line     int
+	Column   int
+	Failure  string // why the test failed, or empty if it passed
+	Duration time.Duration
+}
+
+func (r Result) Passed() bool {
+	return r.Failure == ""
+}
+
+// Position returns where the test is defined, or where it failed if it
+// did, as file:line:column.
+func (r Result) Position() string {
+	if r.Line == 0 {
+		return r.File
+	}
+	return fmt.Sprintf("%s:%d:%d", r.File, r.Line, r.Column)
+}
+
+// Find returns the test files patterns match, sorted and without
+// duplicates. A pattern is a file, a directory, whose test files it
+// matches, or a directory followed by /..., which matches the test files
+// in it and every directory below it but those named testdata or starting
+// with a dot.
+func Find(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if dir, ok := strings.CutSuffix(pattern, "..."); ok {
+			dir = filepath.Clean(dir)
+			err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() && path != dir && (d.Name() == "testdata" || strings.HasPrefix(d.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				if !d.IsDir() && strings.HasSuffix(d.Name(), Suffix) {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			add(pattern)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(pattern, "*"+Suffix))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			add(match)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// RunFile runs the test file at path with an interpreter configured by
+// opts and returns the results of its tests. If the file itself fails,
+// the only result is that of the file.
+func RunFile(path string, opts ...interpreter.Option) []Result {
+	start := time.Now()
+	fileFailed := func(line, column int, format string, a ...any) []Result {
+		return []Result{{
+			File:     path,
+			Line:     line,
+			Column:   column,
+			Failure:  fmt.Sprintf(format, a...),
+			Duration: time.Since(start),
+		}}
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fileFailed(0, 0, "%s", err)
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) != 0 {
+		d := diagnostics[0]
+		return fileFailed(d.Line, d.Column, "%s", d.Message)
+	}
+	tests := testsOf(program)
+
+	opts = append(opts, interpreter.WithWholeProgram(), interpreter.WithFile(path))
+	in := interpreter.New(opts...)
+	err = recovered(func() error {
+		_, err := in.Run(string(src))
+		return err
+	})
+	if err != nil {
+		line, column, message := failure(err)
+		return fileFailed(line, column, "%s", message)
+	}
+	if len(tests) == 0 {
+		return fileFailed(0, 0, "no tests")
+	}
+
+	var results []Result
+	for _, test := range tests {
+		result := Result{File: path, Name: test.Value, Line: test.Token.Line, Column: test.Token.Column}
+		fn, _ := in.Env.Get(test.Value)
+		start := time.Now()
+		err := recovered(func() error {
+			_, err := in.Call(fn)
+			return err
+		})
+		result.Duration = time.Since(start)
+		if err != nil {
+			line, column, message := failure(err)
+			result.Failure = message
+			if line != 0 {
+				result.Line, result.Column = line, column
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// recovered returns the error of run, or one describing the panic that
+// stopped it, so that a file or test that panics the interpreter fails
+// without stopping the tests that follow.
+func recovered(run func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run()
+}
+
+// testsOf returns the names of the tests program defines.
+func testsOf(program *ast.Program) []*ast.Identifier {
+	var tests []*ast.Identifier
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || !strings.HasPrefix(let.Name.Value, Prefix) {
+			continue
+		}
+		if fn, ok := let.Value.(*ast.FunctionLiteral); ok && len(fn.Parameters) == 0 {
+			tests = append(tests, let.Name)
+		}
+	}
+	return tests
+}
+
+// failure returns where the failure err reports happened, if known, and
+// what it is, without the position.
+func failure(err error) (line, column int, message string) {
+	var runtimeErr *interpreter.RuntimeError
+	var resolveErr *interpreter.ResolveError
+	var macroErr *interpreter.MacroError
+	var typeErr *interpreter.TypeError
+	switch {
+	case errors.As(err, &runtimeErr):
+		return runtimeErr.Err.Line, runtimeErr.Err.Column, runtimeErr.Err.Message
+	case errors.As(err, &resolveErr):
+		d := resolveErr.Diagnostics[0]
+		return d.Line, d.Column, d.Message
+	case errors.As(err, &macroErr):
+		d := macroErr.Diagnostics[0]
+		return d.Line, d.Column, fmt.Sprintf("macro %s: %s", d.Macro, d.Message)
+	case errors.As(err, &typeErr):
+		d := typeErr.Diagnostics[0]
+		return d.Line, d.Column, d.Message
+	}
+	return 0, 0, err.Error()
+}
@@ -0,0 +1,190 @@
+// tester/tester_test.go
+
+package tester
+
+import (
+	"bytes"
+	"monkey/interpreter"
+	"monkey/profiler"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, src string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a_test.mk", "a.mk", "sub/b_test.mk", "sub/testdata/c_test.mk", ".git/d_test.mk"} {
+		writeFile(t, filepath.Join(dir, name), "")
+	}
+
+	tests := []struct {
+		patterns []string
+		expected []string
+	}{
+		{[]string{dir + "/..."}, []string{"a_test.mk", "sub/b_test.mk"}},
+		{[]string{dir}, []string{"a_test.mk"}},
+		{[]string{filepath.Join(dir, "sub", "testdata", "c_test.mk"), dir + "/sub/..."}, []string{"sub/b_test.mk", "sub/testdata/c_test.mk"}},
+	}
+
+	for _, tt := range tests {
+		files, err := Find(tt.patterns)
+		if err != nil {
+			t.Fatalf("Find(%v) failed: %s", tt.patterns, err)
+		}
+		var got []string
+		for _, file := range files {
+			rel, _ := filepath.Rel(dir, file)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("wrong files for %v. want=%v, got=%v", tt.patterns, tt.expected, got)
+		}
+	}
+
+	if _, err := Find([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("expected an error for a missing path")
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "math_test.mk")
+	writeFile(t, path, `let add = fn(a, b) { a + b };
+
+let test_add = fn() {
+    assert_eq(add(1, 2), 3);
+};
+
+let test_wrong = fn() {
+    assert_eq(add(1, 2), 4);
+};
+
+let test_type = fn() {
+    add(1, "a");
+};
+
+let test_helper = fn(x) { x };
+let helper = fn() { assert(false) };
+`)
+
+	results := RunFile(path)
+	expected := []string{
+		"test_add " + path + ":3:5 ",
+		"test_wrong " + path + ":8:5 assert_eq: got 3, want 4",
+		"test_type " + path + ":1:24 type mismatch: INTEGER + STRING",
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("wrong number of results. want=%d, got=%d (%v)", len(expected), len(results), results)
+	}
+	for i, r := range results {
+		got := r.Name + " " + r.Position() + " " + r.Failure
+		if got != expected[i] {
+			t.Errorf("wrong result %d. want=%q, got=%q", i, expected[i], got)
+		}
+		if r.Passed() != (r.Failure == "") {
+			t.Errorf("Passed disagrees with Failure for %s", r.Name)
+		}
+	}
+}
+
+func TestRunFileFailures(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"let x = ;", ":1:9 no prefix parse function for ; found"},
+		{"let test_a = fn() { 1 };\nlen(1, 2);", ":2:1 wrong number of arguments. got=2, want=1"},
+		{"let test_a = fn() { y };", ":1:21 identifier not found: y"},
+		{"let a = 1;", " no tests"},
+	}
+
+	for i, tt := range tests {
+		path := filepath.Join(dir, strings.Repeat("x", i+1)+"_test.mk")
+		writeFile(t, path, tt.src)
+
+		results := RunFile(path)
+		if len(results) != 1 || results[0].Name != "" {
+			t.Errorf("expected the file to fail for %q. got=%v", tt.src, results)
+			continue
+		}
+		if got := results[0].Position() + " " + results[0].Failure; got != path+tt.expected {
+			t.Errorf("wrong failure for %q. want=%q, got=%q", tt.src, path+tt.expected, got)
+		}
+	}
+}
+
+func TestRunFilePanics(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "panic_test.mk")
+	writeFile(t, path, `let id = fn(x) { x };
+
+let test_panics = fn() {
+    id(1);
+};
+
+let test_ok = fn() {
+    assert(true);
+};
+`)
+
+	// A profiler that was never started panics on the first function call,
+	// which stands in for a bug in the interpreter.
+	results := RunFile(path, interpreter.WithProfiler(profiler.New(0)))
+	expected := []string{
+		"test_panics " + path + ":3:5 panic: runtime error: index out of range [-1]",
+		"test_ok " + path + ":7:5 ",
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("wrong number of results. want=%d, got=%d (%v)", len(expected), len(results), results)
+	}
+	for i, r := range results {
+		got := r.Name + " " + r.Position() + " " + r.Failure
+		if got != expected[i] {
+			t.Errorf("wrong result %d. want=%q, got=%q", i, expected[i], got)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []Result{
+		{File: "a_test.mk", Name: "test_ok", Line: 1, Column: 5},
+		{File: "a_test.mk", Name: "test_bad", Line: 4, Column: 5, Failure: `assert_eq: got 1, want "<2>"`},
+		{File: "b_test.mk", Line: 1, Column: 9, Failure: "no tests"},
+	}
+
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="2" time="0.000">
+  <testsuite name="a_test.mk" tests="2" failures="1" time="0.000">
+    <testcase name="test_ok" classname="a_test.mk" time="0.000"></testcase>
+    <testcase name="test_bad" classname="a_test.mk" time="0.000">
+      <failure message="assert_eq: got 1, want &#34;&lt;2&gt;&#34;">a_test.mk:4:5: assert_eq: got 1, want &#34;&lt;2&gt;&#34;</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="b_test.mk" tests="1" failures="1" time="0.000">
+    <testcase name="b_test.mk" classname="b_test.mk" time="0.000">
+      <failure message="no tests">b_test.mk:1:9: no tests</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != expected {
+		t.Errorf("wrong report.\nwant=%s\ngot=%s", expected, out.String())
+	}
+}
@@ -7,11 +7,14 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // 1-based line of the first character
+	Column  int // 1-based column of the first character, in bytes
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT"
 
 	IDENT  = "IDENT"
 	INT    = "INT"
@@ -27,6 +30,7 @@ const (
 	BANG     = "!"
 	EQ       = "=="
 	NOT_EQ   = "!="
+	ARROW    = "->"
 
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -46,10 +50,17 @@ const (
 	RETURN   = "RETURN"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	NULL     = "NULL"
 
 	MACRO = "MACRO"
+
+	IMPORT = "IMPORT"
+	EXPORT = "EXPORT"
 )
 
+// keywords are reserved and cannot be bound by let. null is one of them,
+// so a program that said `let null = ...` before null was a literal no
+// longer parses and must pick another name.
 var keywords = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
@@ -58,7 +69,10 @@ var keywords = map[string]TokenType{
 	"return": RETURN,
 	"true":   TRUE,
 	"false":  FALSE,
+	"null":   NULL,
 	"macro":  MACRO,
+	"import": IMPORT,
+	"export": EXPORT,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -67,3 +81,12 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// Keywords returns the reserved words of the language.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	return words
+}
@@ -0,0 +1,567 @@
+// types/check.go
+
+package types
+
+import (
+	"fmt"
+	"monkey/ast"
+)
+
+// Diagnostic is a type error found in a program.
+type Diagnostic struct {
+	Line, Column int
+	Message      string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// Result is the result of checking a program.
+type Result struct {
+	// Types maps the expressions of the program, and the names its lets
+	// and parameters bind, to their types. The type of a let name is
+	// generalized: a variable in it stands for any type.
+	Types       map[ast.Node]Type
+	Diagnostics []Diagnostic
+}
+
+// builtins are the types of the builtin functions. Those that accept
+// values of several unrelated types, such as puts, are any.
+var builtins = map[string]func(u *unifier) Type{
+	"len": func(u *unifier) Type { return &Function{Params: []Type{Any}, Result: Int} },
+	"str": func(u *unifier) Type { return &Function{Params: []Type{Any}, Result: String} },
+	"first": func(u *unifier) Type {
+		a := u.fresh()
+		return &Function{Params: []Type{&Array{Element: a}}, Result: a}
+	},
+	"last": func(u *unifier) Type {
+		a := u.fresh()
+		return &Function{Params: []Type{&Array{Element: a}}, Result: a}
+	},
+	"rest": func(u *unifier) Type {
+		a := u.fresh()
+		return &Function{Params: []Type{&Array{Element: a}}, Result: &Array{Element: a}}
+	},
+	"push": func(u *unifier) Type {
+		a := u.fresh()
+		return &Function{Params: []Type{&Array{Element: a}, a}, Result: &Array{Element: a}}
+	},
+}
+
+// Annotated reports whether program has a type annotation anywhere in it.
+func Annotated(program *ast.Program) bool {
+	found := false
+	ast.Walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			found = found || node.Type != nil
+		case *ast.FunctionLiteral:
+			found = found || node.ReturnType != nil
+		}
+		return !found
+	})
+	return found
+}
+
+// Check infers the types of program and reports the type errors in it.
+// Names the program uses but does not bind, other than builtins, are taken
+// to be of type any.
+func Check(program *ast.Program) *Result {
+	c := &checker{result: &Result{Types: make(map[ast.Node]Type)}}
+	top := newScope(nil, nil)
+	c.declare(top, program.Statements)
+	for _, stmt := range program.Statements {
+		c.statement(top, stmt)
+	}
+
+	for node, t := range c.result.Types {
+		c.result.Types[node] = resolve(t)
+	}
+	return c.result
+}
+
+// binding is what a name is bound to in a scope.
+type binding struct {
+	t      Type
+	scheme *scheme
+
+	// pending is set for a let binding that has not been checked yet,
+	// which functions may still refer to. used is set once one does.
+	pending bool
+	used    bool
+}
+
+// scope holds the names bound in a function body or at the top level.
+// Blocks share the scope they are in, as they do when evaluated.
+type scope struct {
+	parent *scope
+	fn     *function
+	names  map[string]*binding
+}
+
+func newScope(parent *scope, fn *function) *scope {
+	return &scope{parent: parent, fn: fn, names: make(map[string]*binding)}
+}
+
+// function is what is known of the result of the function being checked.
+type function struct {
+	declared Type // the annotated result, or nil
+	returned Type // the join of the values returned so far, or nil
+}
+
+type checker struct {
+	unifier
+	result *Result
+}
+
+func (c *checker) errorf(node ast.Node, format string, args ...interface{}) {
+	line, column := ast.Position(node)
+	c.result.Diagnostics = append(c.result.Diagnostics, Diagnostic{
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) setType(node ast.Node, t Type) Type {
+	c.result.Types[node] = t
+	return t
+}
+
+// declare binds the names of the let statements in statements that belong
+// to s before any of them is checked, since functions may refer to lets
+// that follow them. A name bound by more than one let is of type any until
+// its lets are checked. Imported modules are of type any.
+func (c *checker) declare(s *scope, statements []ast.Statement) {
+	lets := make(map[string]int)
+	imports := make(map[string]bool)
+	for _, stmt := range statements {
+		ast.Walk(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.LetStatement:
+				lets[node.Name.Value]++
+			case *ast.ImportStatement:
+				imports[node.Name.Value] = true
+			case *ast.FunctionLiteral, *ast.MacroLiteral:
+				return false
+			}
+			return true
+		})
+	}
+	for name, n := range lets {
+		var t Type = Any
+		if n == 1 {
+			t = c.fresh()
+		}
+		s.names[name] = &binding{t: t, pending: true}
+	}
+	for name := range imports {
+		s.names[name] = &binding{t: Any, pending: true}
+	}
+}
+
+// annotation returns the type an annotation stands for, or a fresh
+// variable if there is none.
+func (c *checker) annotation(te ast.TypeExpression) Type {
+	switch te := te.(type) {
+	case nil:
+		return c.fresh()
+	case *ast.NamedType:
+		switch te.Name {
+		case "int":
+			return Int
+		case "string":
+			return String
+		case "bool":
+			return Bool
+		case "null":
+			return Null
+		case "any":
+			return Any
+		}
+		c.errorf(te, "unknown type %s", te.Name)
+		return Any
+	case *ast.ArrayType:
+		return &Array{Element: c.annotation(te.Element)}
+	case *ast.HashType:
+		return &Hash{Key: c.annotation(te.Key), Value: c.annotation(te.Value)}
+	case *ast.FunctionType:
+		params := make([]Type, len(te.Parameters))
+		for i, param := range te.Parameters {
+			params[i] = c.annotation(param)
+		}
+		return &Function{Params: params, Result: c.annotation(te.Result)}
+	}
+	return Any
+}
+
+// statement checks stmt and returns the type of the value it leaves as the
+// value of the block it ends.
+func (c *checker) statement(s *scope, stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(s, stmt)
+		return Null
+	case *ast.ImportStatement:
+		s.names[stmt.Name.Value] = &binding{t: Any}
+		c.setType(stmt.Name, Any)
+		return Null
+	case *ast.ReturnStatement:
+		c.ret(s, stmt)
+		// Nothing follows a return, so the block it ends has no value
+		// of its own.
+		return c.fresh()
+	case *ast.ExpressionStatement:
+		return c.expression(s, stmt.Expression)
+	case *ast.BlockStatement:
+		return c.block(s, stmt)
+	}
+	return Any
+}
+
+func (c *checker) block(s *scope, block *ast.BlockStatement) Type {
+	var t Type = Null
+	for _, stmt := range block.Statements {
+		t = c.statement(s, stmt)
+	}
+	return t
+}
+
+func (c *checker) let(s *scope, stmt *ast.LetStatement) {
+	var t Type
+	if stmt.Name.Type != nil {
+		t = c.annotation(stmt.Name.Type)
+	}
+	c.level++
+	if t != nil {
+		c.expect(s, stmt.Value, t, "let "+stmt.Name.Value)
+	} else {
+		t = c.expression(s, stmt.Value)
+	}
+	c.level--
+
+	b := s.names[stmt.Name.Value]
+	if b != nil && b.pending && b.used && !c.unify(b.t, t) {
+		c.errorf(stmt.Value, "cannot use %s as %s in let %s", t, b.t, stmt.Name.Value)
+	}
+	sch := c.generalize(t)
+	s.names[stmt.Name.Value] = &binding{scheme: sch}
+	c.setType(stmt.Name, t)
+}
+
+func (c *checker) ret(s *scope, stmt *ast.ReturnStatement) {
+	fn := s.fn
+	if fn != nil && fn.declared != nil {
+		c.expect(s, stmt.ReturnValue, fn.declared, "return")
+		return
+	}
+
+	t := c.expression(s, stmt.ReturnValue)
+	switch {
+	case fn == nil:
+	case fn.returned == nil:
+		fn.returned = t
+	default:
+		fn.returned = c.join(fn.returned, t)
+	}
+}
+
+// expect checks e where its context, named by what, expects a value of type
+// want. The branches of an if expression and the elements and entries of a
+// literal are each checked against the part of want they give, so that a
+// mismatch is reported where it is rather than joined into any.
+func (c *checker) expect(s *scope, e ast.Expression, want Type, what string) {
+	switch e := e.(type) {
+	case *ast.IfExpression:
+		if e.Alternative != nil {
+			c.expression(s, e.Condition)
+			c.expectBlock(s, e.Consequence, want, what)
+			c.expectBlock(s, e.Alternative, want, what)
+			c.setType(e, want)
+			return
+		}
+	case *ast.ArrayLiteral:
+		if arr, ok := prune(want).(*Array); ok {
+			for _, el := range e.Elements {
+				c.expect(s, el, arr.Element, what)
+			}
+			c.setType(e, want)
+			return
+		}
+	case *ast.HashLiteral:
+		if hash, ok := prune(want).(*Hash); ok {
+			for _, k := range e.Keys() {
+				c.expect(s, k, hash.Key, what)
+				c.expect(s, e.Pairs[k], hash.Value, what)
+			}
+			c.setType(e, want)
+			return
+		}
+	}
+
+	if t := c.expression(s, e); !c.unify(t, want) {
+		c.errorf(e, "cannot use %s as %s in %s", t, want, what)
+	}
+}
+
+// expectBlock checks block, whose value its context expects to be of type
+// want, as expect does.
+func (c *checker) expectBlock(s *scope, block *ast.BlockStatement, want Type, what string) {
+	n := len(block.Statements)
+	if n == 0 {
+		if !c.unify(Null, want) {
+			c.errorf(block, "cannot use %s as %s in %s", Null, want, what)
+		}
+		return
+	}
+	for _, stmt := range block.Statements[:n-1] {
+		c.statement(s, stmt)
+	}
+
+	last := block.Statements[n-1]
+	if stmt, ok := last.(*ast.ExpressionStatement); ok {
+		c.expect(s, stmt.Expression, want, what)
+		return
+	}
+	if t := c.statement(s, last); !c.unify(t, want) {
+		c.errorf(last, "cannot use %s as %s in %s", t, want, what)
+	}
+}
+
+func (c *checker) expression(s *scope, e ast.Expression) Type {
+	if e == nil {
+		return Any
+	}
+	return c.setType(e, c.infer(s, e))
+}
+
+func (c *checker) infer(s *scope, e ast.Expression) Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.NullLiteral:
+		return Null
+	case *ast.Identifier:
+		return c.identifier(s, e)
+	case *ast.PrefixExpression:
+		return c.prefix(s, e)
+	case *ast.InfixExpression:
+		return c.infix(s, e)
+	case *ast.IfExpression:
+		c.expression(s, e.Condition)
+		consequence := c.block(s, e.Consequence)
+		if e.Alternative == nil {
+			// The value is null when the condition is false.
+			if prune(consequence) == Null {
+				return Null
+			}
+			return Any
+		}
+		return c.join(consequence, c.block(s, e.Alternative))
+	case *ast.FunctionLiteral:
+		return c.function(s, e)
+	case *ast.MacroLiteral:
+		return Any
+	case *ast.CallExpression:
+		return c.call(s, e)
+	case *ast.IndexExpression:
+		return c.index(s, e)
+	case *ast.ArrayLiteral:
+		var elem Type = c.fresh()
+		for i, el := range e.Elements {
+			t := c.expression(s, el)
+			if i == 0 {
+				elem = t
+			} else {
+				elem = c.join(elem, t)
+			}
+		}
+		return &Array{Element: elem}
+	case *ast.HashLiteral:
+		var key, value Type = c.fresh(), c.fresh()
+		for i, k := range e.Keys() {
+			kt, vt := c.expression(s, k), c.expression(s, e.Pairs[k])
+			if i == 0 {
+				key, value = kt, vt
+			} else {
+				key, value = c.join(key, kt), c.join(value, vt)
+			}
+		}
+		return &Hash{Key: key, Value: value}
+	}
+	return Any
+}
+
+func (c *checker) identifier(s *scope, ident *ast.Identifier) Type {
+	for sc := s; sc != nil; sc = sc.parent {
+		b, ok := sc.names[ident.Value]
+		if !ok {
+			continue
+		}
+		if b.scheme != nil {
+			return c.instantiate(b.scheme)
+		}
+		b.used = true
+		return b.t
+	}
+	if builtin, ok := builtins[ident.Value]; ok {
+		return builtin(&c.unifier)
+	}
+	return Any
+}
+
+func (c *checker) prefix(s *scope, e *ast.PrefixExpression) Type {
+	right := c.expression(s, e.Right)
+	switch e.Operator {
+	case "!":
+		return Bool
+	case "-":
+		if !c.unify(right, Int) {
+			c.errorf(e, "unknown operator: -%s", right)
+		}
+		return Int
+	}
+	return Any
+}
+
+func (c *checker) infix(s *scope, e *ast.InfixExpression) Type {
+	left := c.expression(s, e.Left)
+	right := c.expression(s, e.Right)
+
+	switch e.Operator {
+	case "==", "!=":
+		// Values of different types are unequal rather than an error,
+		// but strings cannot be compared.
+		if prune(left) == String && prune(right) == String {
+			c.errorf(e, "unknown operator: %s %s %s", left, e.Operator, right)
+		}
+		return Bool
+	}
+
+	if !c.unify(left, right) {
+		c.errorf(e, "type mismatch: %s %s %s", left, e.Operator, right)
+		return Any
+	}
+	switch e.Operator {
+	case "+":
+		// Both integers and strings can be added, so an operand whose
+		// type is still unknown stays unknown.
+		t := prune(left)
+		if t == Any {
+			t = prune(right)
+		}
+		switch t {
+		case Int, String, Any:
+			return t
+		default:
+			if _, ok := t.(*Var); ok {
+				return t
+			}
+			c.errorf(e, "unknown operator: %s %s %s", left, e.Operator, right)
+			return Any
+		}
+	case "-", "*", "/", "<", ">":
+		if !c.unify(left, Int) {
+			c.errorf(e, "unknown operator: %s %s %s", left, e.Operator, right)
+		}
+		if e.Operator == "<" || e.Operator == ">" {
+			return Bool
+		}
+		return Int
+	}
+	return Any
+}
+
+func (c *checker) function(s *scope, fn *ast.FunctionLiteral) Type {
+	f := &function{}
+	if fn.ReturnType != nil {
+		f.declared = c.annotation(fn.ReturnType)
+	}
+	inner := newScope(s, f)
+
+	params := make([]Type, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = c.setType(param, c.annotation(param.Type))
+		inner.names[param.Value] = &binding{t: params[i]}
+	}
+
+	c.declare(inner, fn.Body.Statements)
+	if f.declared != nil {
+		c.expectBlock(inner, fn.Body, f.declared, "function result")
+		return &Function{Params: params, Result: f.declared}
+	}
+
+	body := c.block(inner, fn.Body)
+	result := body
+	if f.returned != nil {
+		result = c.join(f.returned, body)
+	}
+	return &Function{Params: params, Result: result}
+}
+
+func (c *checker) call(s *scope, e *ast.CallExpression) Type {
+	if e.Function.TokenLiteral() == "quote" {
+		// The arguments are not evaluated.
+		return Any
+	}
+
+	callee := c.expression(s, e.Function)
+	args := make([]Type, len(e.Arguments))
+	for i, arg := range e.Arguments {
+		args[i] = c.expression(s, arg)
+	}
+
+	switch f := prune(callee).(type) {
+	case *Function:
+		if len(f.Params) != len(args) {
+			c.errorf(e, "wrong number of arguments: want=%d, got=%d", len(f.Params), len(args))
+			return f.Result
+		}
+		for i, arg := range args {
+			if !c.unify(arg, f.Params[i]) {
+				c.errorf(e.Arguments[i], "cannot use %s as %s in argument %d to %s", arg, f.Params[i], i+1, e.Function)
+			}
+		}
+		return f.Result
+	case *Var:
+		result := c.fresh()
+		c.unify(f, &Function{Params: args, Result: result})
+		return result
+	default:
+		if f != Any {
+			c.errorf(e, "not a function: %s", f)
+		}
+		return Any
+	}
+}
+
+func (c *checker) index(s *scope, e *ast.IndexExpression) Type {
+	left := c.expression(s, e.Left)
+	index := c.expression(s, e.Index)
+
+	switch l := prune(left).(type) {
+	case *Array:
+		if !c.unify(index, Int) {
+			c.errorf(e.Index, "cannot use %s as int in index", index)
+		}
+		return l.Element
+	case *Hash:
+		if !c.unify(index, l.Key) {
+			c.errorf(e.Index, "cannot use %s as %s in index", index, l.Key)
+		}
+		return l.Value
+	case *Var:
+		// It could be an array or a hash.
+		return Any
+	default:
+		if l != Any {
+			c.errorf(e, "index operator not supported: %s", l)
+		}
+		return Any
+	}
+}
@@ -0,0 +1,172 @@
+// types/check_test.go
+
+package types
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func check(t *testing.T, input string) (*ast.Program, *Result) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program, Check(program)
+}
+
+// letTypes returns the type of each top-level let name of program.
+func letTypes(program *ast.Program, result *Result) map[string]string {
+	types := make(map[string]string)
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			types[let.Name.Value] = result.Types[let.Name].String()
+		}
+	}
+	return types
+}
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{`let x = 1 + 2;`, "x", "int"},
+		{`let s = "a" + "b";`, "s", "string"},
+		{`let b = 1 < 2;`, "b", "bool"},
+		{`let n = !5;`, "n", "bool"},
+		{`let xs = [1, 2, 3];`, "xs", "[int]"},
+		{`let xs = [];`, "xs", "[a]"},
+		{`let mixed = [1, "two"];`, "mixed", "[any]"},
+		{`let h = {"a": 1, "b": 2};`, "h", "{string: int}"},
+		{`let id = fn(x) { x };`, "id", "fn(a) -> a"},
+		{`let add = fn(a, b) { a + b };`, "add", "fn(a, a) -> a"},
+		{`let inc = fn(a) { a + 1 };`, "inc", "fn(int) -> int"},
+		{`let apply = fn(f, x) { f(x) };`, "apply", "fn(fn(a) -> b, a) -> b"},
+		{`let id = fn(x) { x }; let pair = [id(1), id(2)];`, "pair", "[int]"},
+		{`let id = fn(x) { x }; let s = id("s");`, "s", "string"},
+		{`let f = fn(xs) { first(xs) + 1 };`, "f", "fn([int]) -> int"},
+		{`let y = if (true) { 1 } else { 2 };`, "y", "int"},
+		{`let y = if (true) { 1 };`, "y", "any"},
+		{`let y = if (true) { 1 } else { "one" };`, "y", "any"},
+		{`let f = fn(n) { if (n < 1) { return "small" }; "big" };`, "f", "fn(int) -> string"},
+		{`let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };`, "fact", "fn(int) -> int"},
+		{`let f = fn() { g() }; let g = fn() { 1 };`, "f", "fn() -> int"},
+		{`let x: any = 1;`, "x", "any"},
+		{`let f = fn(a: string, b: [int]) -> bool { len(a) < len(b) };`, "f", "fn(string, [int]) -> bool"},
+		{`let f: fn(int) -> int = fn(x) { x };`, "f", "fn(int) -> int"},
+		{`let x = puts(1, "a");`, "x", "any"},
+		{`let x = undefined + 1;`, "x", "int"},
+	}
+
+	for _, tt := range tests {
+		program, result := check(t, tt.input)
+		if len(result.Diagnostics) != 0 {
+			t.Errorf("unexpected diagnostics for %q: %v", tt.input, result.Diagnostics)
+			continue
+		}
+		if got := letTypes(program, result)[tt.name]; got != tt.expected {
+			t.Errorf("wrong type of %s in %q. want=%q, got=%q", tt.name, tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`1 + "a"`, []string{"1:3: type mismatch: int + string"}},
+		{`true + false`, []string{"1:6: unknown operator: bool + bool"}},
+		{`"a" == "b"`, []string{"1:5: unknown operator: string == string"}},
+		{`-"a"`, []string{"1:1: unknown operator: -string"}},
+		{`let x: int = "a";`, []string{"1:14: cannot use string as int in let x"}},
+		{`let x: number = 1;`, []string{"1:8: unknown type number"}},
+		{
+			`let f = fn(a: string) { a }; f(1);`,
+			[]string{"1:32: cannot use int as string in argument 1 to f"},
+		},
+		{`let f = fn(a, b) { a }; f(1);`, []string{"1:26: wrong number of arguments: want=2, got=1"}},
+		{`let f = fn() -> int { "a" };`, []string{"1:23: cannot use string as int in function result"}},
+		{`let f = fn(n) -> int { return "a" };`, []string{"1:31: cannot use string as int in return"}},
+		{`let x = 1; x(2)`, []string{"1:13: not a function: int"}},
+		{`let x = 1; x[0]`, []string{"1:13: index operator not supported: int"}},
+		{`let xs = [1]; xs["a"]`, []string{"1:18: cannot use string as int in index"}},
+		{`let h = {"a": 1}; h[1]`, []string{"1:21: cannot use int as string in index"}},
+		{`let add = fn(a, b) { a + b }; add(1, "a")`, []string{"1:38: cannot use string as int in argument 2 to add"}},
+		{`push([1], "a")`, []string{"1:11: cannot use string as int in argument 2 to push"}},
+		{`let f = fn(x: int) { x }; let g = fn() { f("a") };`, []string{"1:44: cannot use string as int in argument 1 to f"}},
+		{`if (false) { 1 + true }`, []string{"1:16: type mismatch: int + bool"}},
+		{`let a: [int] = [1, "a"];`, []string{"1:20: cannot use string as int in let a"}},
+		{`let x: int = if (true) { 1 } else { "a" };`, []string{"1:37: cannot use string as int in let x"}},
+		{`fn(n: int) -> int { if (n > 0) { n } else { "neg" } }`, []string{"1:45: cannot use string as int in function result"}},
+		{`let h: {string: int} = {"a": 1, "b": true};`, []string{"1:38: cannot use bool as int in let h"}},
+		{`let f = fn(n) -> [int] { return [n, "a"] };`, []string{"1:37: cannot use string as int in return"}},
+		{`let f = fn() -> int { };`, []string{"1:21: cannot use null as int in function result"}},
+	}
+
+	for _, tt := range tests {
+		_, result := check(t, tt.input)
+		var got []string
+		for _, d := range result.Diagnostics {
+			got = append(got, d.String())
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("wrong diagnostics for %q. want=%q, got=%q", tt.input, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("wrong diagnostic for %q. want=%q, got=%q", tt.input, tt.expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestGradual(t *testing.T) {
+	// Dynamically typed code the checker cannot type precisely is left
+	// alone.
+	inputs := []string{
+		`let xs = [1, "two", true]; puts(xs[0]);`,
+		`let h = {1: "one", "two": 2}; h[1];`,
+		`let f = fn(x: any) { x + 1 }; f("a");`,
+		`let g = fn(x) { if (x) { 1 } }; g(true) + 1;`,
+		`let x = 1; let x = "a"; x + "b";`,
+		`let m = macro(a) { quote(unquote(a) + "x") }; quote(1 + "a");`,
+		`let apply = fn(f) { f(1) }; apply(fn(x) { x + 1 }); apply(len);`,
+		`let a: [any] = [1, "a"]; let y: any = if (true) { 1 } else { "a" };`,
+		`let f = fn() { m["a"] + 1; m["b"] + "s" }; import "lib.mk" as m;`,
+		`let m = 1; import "lib.mk" as m; m["a"];`,
+	}
+
+	for _, input := range inputs {
+		_, result := check(t, input)
+		if len(result.Diagnostics) != 0 {
+			t.Errorf("unexpected diagnostics for %q: %v", input, result.Diagnostics)
+		}
+	}
+}
+
+func TestAnnotated(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`let x = fn(a) { a };`, false},
+		{`let x: int = 1;`, true},
+		{`let f = fn() { fn(a: int) { a } };`, true},
+		{`let f = fn() -> int { 1 };`, true},
+	}
+
+	for _, tt := range tests {
+		program, _ := check(t, tt.input)
+		if got := Annotated(program); got != tt.expected {
+			t.Errorf("Annotated(%q) wrong. want=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
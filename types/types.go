@@ -0,0 +1,349 @@
+// types/types.go
+
+// Package types checks the types of Monkey programs before they run.
+// Annotations on lets, parameters and function results, as in
+//
+//	let add = fn(a: int, b: int) -> int { a + b };
+//
+// are checked, and the types of unannotated code are inferred in the
+// Hindley-Milner style: an unannotated parameter gets a type variable that
+// its uses constrain, and a function bound by let is generalized, so that
+// let id = fn(x) { x } can be applied to values of any type.
+//
+// The typing is gradual. any is the type of values nothing is known about
+// statically; it is compatible with every type. The checker falls back to
+// it wherever the dynamic typing of Monkey cannot be expressed, as for an
+// array mixing integers and strings, an if without an else, or a name bound
+// outside the program. Where an annotation says what type a value must be,
+// the branches, elements and entries of that value are each checked
+// against it instead.
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the type of a Monkey value.
+type Type interface {
+	String() string
+}
+
+// Basic is a type without parts.
+type Basic struct {
+	Name string
+}
+
+var (
+	Int    = &Basic{Name: "int"}
+	String = &Basic{Name: "string"}
+	Bool   = &Basic{Name: "bool"}
+	Null   = &Basic{Name: "null"}
+
+	// Any is the type of values whose type is not known statically.
+	Any = &Basic{Name: "any"}
+)
+
+func (b *Basic) String() string { return b.Name }
+
+// Array is the type of arrays whose elements are of type Element.
+type Array struct {
+	Element Type
+}
+
+func (a *Array) String() string { return format(a) }
+
+// Hash is the type of hashes from keys of type Key to values of type Value.
+type Hash struct {
+	Key, Value Type
+}
+
+func (h *Hash) String() string { return format(h) }
+
+// Function is the type of functions taking arguments of the types in
+// Params and returning a value of type Result.
+type Function struct {
+	Params []Type
+	Result Type
+}
+
+func (f *Function) String() string { return format(f) }
+
+// Var is a type variable, standing for a type that is not known yet or, in
+// the type of a generalized let binding, for any type at all.
+type Var struct {
+	id    int
+	level int  // how deeply nested in let values it was created
+	bound Type // the type it has been unified with, if any
+}
+
+func (v *Var) String() string { return format(v) }
+
+// format writes t, naming its unbound variables a, b, c and so on in the
+// order they appear.
+func format(t Type) string {
+	var out strings.Builder
+	writeType(&out, t, make(map[*Var]string))
+	return out.String()
+}
+
+func writeType(out *strings.Builder, t Type, names map[*Var]string) {
+	switch t := prune(t).(type) {
+	case *Basic:
+		out.WriteString(t.Name)
+	case *Array:
+		out.WriteString("[")
+		writeType(out, t.Element, names)
+		out.WriteString("]")
+	case *Hash:
+		out.WriteString("{")
+		writeType(out, t.Key, names)
+		out.WriteString(": ")
+		writeType(out, t.Value, names)
+		out.WriteString("}")
+	case *Function:
+		out.WriteString("fn(")
+		for i, param := range t.Params {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			writeType(out, param, names)
+		}
+		out.WriteString(") -> ")
+		writeType(out, t.Result, names)
+	case *Var:
+		name, ok := names[t]
+		if !ok {
+			if n := len(names); n < 26 {
+				name = string(rune('a' + n))
+			} else {
+				name = fmt.Sprintf("t%d", n)
+			}
+			names[t] = name
+		}
+		out.WriteString(name)
+	}
+}
+
+// prune returns the type t stands for, following bound variables.
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.bound == nil {
+			return t
+		}
+		t = v.bound
+	}
+}
+
+// resolve returns t with every bound variable in it replaced by its type.
+func resolve(t Type) Type {
+	switch t := prune(t).(type) {
+	case *Array:
+		return &Array{Element: resolve(t.Element)}
+	case *Hash:
+		return &Hash{Key: resolve(t.Key), Value: resolve(t.Value)}
+	case *Function:
+		params := make([]Type, len(t.Params))
+		for i, param := range t.Params {
+			params[i] = resolve(param)
+		}
+		return &Function{Params: params, Result: resolve(t.Result)}
+	default:
+		return t
+	}
+}
+
+// scheme is the type of a let binding generalized over vars, which every
+// use of the binding replaces with fresh variables.
+type scheme struct {
+	vars []*Var
+	t    Type
+}
+
+// unifier binds type variables, keeping a trail of what it changed while
+// trying out a unification so that it can be undone.
+type unifier struct {
+	nextID int
+	level  int
+
+	trying int
+	trail  []func()
+}
+
+func (u *unifier) fresh() *Var {
+	u.nextID++
+	return &Var{id: u.nextID, level: u.level}
+}
+
+// unify makes a and b the same type, binding variables in them as needed,
+// and reports whether it could. any unifies with every type without
+// binding anything.
+func (u *unifier) unify(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == Any || b == Any {
+		return true
+	}
+	if v, ok := a.(*Var); ok {
+		return u.bind(v, b)
+	}
+	if v, ok := b.(*Var); ok {
+		return u.bind(v, a)
+	}
+
+	switch a := a.(type) {
+	case *Basic:
+		return a == b
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && u.unify(a.Element, b.Element)
+	case *Hash:
+		b, ok := b.(*Hash)
+		return ok && u.unify(a.Key, b.Key) && u.unify(a.Value, b.Value)
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !u.unify(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		return u.unify(a.Result, b.Result)
+	}
+	return false
+}
+
+func (u *unifier) bind(v *Var, t Type) bool {
+	if v == t {
+		return true
+	}
+	if u.occurs(v, t) {
+		return false
+	}
+	u.record(func() { v.bound = nil })
+	v.bound = t
+	return true
+}
+
+// occurs reports whether v appears in t, which would make binding v to t
+// build an infinite type. It moves the variables of t out to the level of
+// v, so that they are generalized no sooner than v is.
+func (u *unifier) occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		if t == v {
+			return true
+		}
+		if t.level > v.level {
+			level := t.level
+			u.record(func() { t.level = level })
+			t.level = v.level
+		}
+	case *Array:
+		return u.occurs(v, t.Element)
+	case *Hash:
+		return u.occurs(v, t.Key) || u.occurs(v, t.Value)
+	case *Function:
+		for _, param := range t.Params {
+			if u.occurs(v, param) {
+				return true
+			}
+		}
+		return u.occurs(v, t.Result)
+	}
+	return false
+}
+
+// record adds undo to the trail if a unification is being tried out.
+func (u *unifier) record(undo func()) {
+	if u.trying > 0 {
+		u.trail = append(u.trail, undo)
+	}
+}
+
+// join returns the type of a value that may be of type a or of type b:
+// their unification if they have one, and any otherwise.
+func (u *unifier) join(a, b Type) Type {
+	mark := len(u.trail)
+	u.trying++
+	ok := u.unify(a, b)
+	u.trying--
+
+	if !ok {
+		for i := len(u.trail) - 1; i >= mark; i-- {
+			u.trail[i]()
+		}
+		u.trail = u.trail[:mark]
+		return Any
+	}
+	if u.trying == 0 {
+		u.trail = u.trail[:0]
+	}
+	return a
+}
+
+// generalize returns the scheme of t quantified over its variables created
+// in let values nested deeper than the current one.
+func (u *unifier) generalize(t Type) *scheme {
+	s := &scheme{t: t}
+	seen := make(map[*Var]bool)
+	var collect func(Type)
+	collect = func(t Type) {
+		switch t := prune(t).(type) {
+		case *Var:
+			if t.level > u.level && !seen[t] {
+				seen[t] = true
+				s.vars = append(s.vars, t)
+			}
+		case *Array:
+			collect(t.Element)
+		case *Hash:
+			collect(t.Key)
+			collect(t.Value)
+		case *Function:
+			for _, param := range t.Params {
+				collect(param)
+			}
+			collect(t.Result)
+		}
+	}
+	collect(t)
+	return s
+}
+
+// instantiate returns the type of s with fresh variables for those it is
+// quantified over.
+func (u *unifier) instantiate(s *scheme) Type {
+	if len(s.vars) == 0 {
+		return s.t
+	}
+	fresh := make(map[*Var]Type)
+	for _, v := range s.vars {
+		fresh[v] = u.fresh()
+	}
+	var subst func(Type) Type
+	subst = func(t Type) Type {
+		switch t := prune(t).(type) {
+		case *Var:
+			if f, ok := fresh[t]; ok {
+				return f
+			}
+			return t
+		case *Array:
+			return &Array{Element: subst(t.Element)}
+		case *Hash:
+			return &Hash{Key: subst(t.Key), Value: subst(t.Value)}
+		case *Function:
+			params := make([]Type, len(t.Params))
+			for i, param := range t.Params {
+				params[i] = subst(param)
+			}
+			return &Function{Params: params, Result: subst(t.Result)}
+		default:
+			return t
+		}
+	}
+	return subst(s.t)
+}
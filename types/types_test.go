@@ -0,0 +1,68 @@
+// types/types_test.go
+
+package types
+
+import "testing"
+
+func TestString(t *testing.T) {
+	u := &unifier{}
+	a, b := u.fresh(), u.fresh()
+
+	tests := []struct {
+		t        Type
+		expected string
+	}{
+		{Int, "int"},
+		{&Array{Element: &Hash{Key: String, Value: Any}}, "[{string: any}]"},
+		{&Function{Params: []Type{b, a}, Result: b}, "fn(a, b) -> a"},
+		{&Function{Params: []Type{}, Result: &Function{Params: []Type{Null}, Result: Bool}}, "fn() -> fn(null) -> bool"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.t.String(); got != tt.expected {
+			t.Errorf("wrong string. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestUnify(t *testing.T) {
+	u := &unifier{}
+	a := u.fresh()
+
+	if !u.unify(&Array{Element: a}, &Array{Element: Int}) {
+		t.Fatalf("[a] and [int] did not unify")
+	}
+	if prune(a) != Int {
+		t.Errorf("a not bound to int. got=%s", prune(a))
+	}
+	if u.unify(a, String) {
+		t.Errorf("int and string unified")
+	}
+	if !u.unify(Any, &Function{Params: []Type{String}, Result: Null}) {
+		t.Errorf("any did not unify with a function")
+	}
+
+	b := u.fresh()
+	if u.unify(b, &Array{Element: b}) {
+		t.Errorf("b unified with [b]")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	u := &unifier{}
+	a, b := u.fresh(), u.fresh()
+
+	// The failed unification of the results must not leave a bound.
+	joined := u.join(&Function{Params: []Type{a}, Result: Int}, &Function{Params: []Type{String}, Result: Bool})
+	if joined != Any {
+		t.Errorf("wrong join. want=any, got=%s", joined)
+	}
+	if prune(a) != a {
+		t.Errorf("a left bound to %s", prune(a))
+	}
+
+	joined = u.join(&Array{Element: b}, &Array{Element: Int})
+	if joined.String() != "[int]" {
+		t.Errorf("wrong join. want=[int], got=%s", joined)
+	}
+}
@@ -1,6 +1,8 @@
 // utils/utils.go
 package utils
 
+import "strings"
+
 func IsLetter(ch byte) bool {
 	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
 }
@@ -8,3 +10,27 @@ func IsLetter(ch byte) bool {
 func IsDigit(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
+
+// Quote returns s as a Monkey string literal, escaping backslashes, double
+// quotes, newlines, tabs and carriage returns.
+func Quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\', '"':
+			out.WriteByte('\\')
+			out.WriteByte(s[i])
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}